@@ -0,0 +1,183 @@
+package errors
+
+import (
+	stderrors "errors"
+	"fmt"
+	"io"
+
+	"github.com/cosiner/gohper/runtime2"
+)
+
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Error is a structured error with code, message, key/value fields, cause and
+// the call stack where it's created.
+//
+// It works with Is/As, an Error matches target if target is an *Error with
+// the same non-empty code.
+type Error struct {
+	Code    string
+	Message string
+	Fields  []Field
+	Cause   error
+	Stack   runtime2.CallStack
+}
+
+// NewError create an error with code and message, call stack is captured
+func NewError(code, msg string) *Error {
+	return &Error{
+		Code:    code,
+		Message: msg,
+		Stack:   runtime2.Callers(1),
+	}
+}
+
+// NewErrorf is same as NewError, but format message
+func NewErrorf(code, format string, v ...interface{}) *Error {
+	return &Error{
+		Code:    code,
+		Message: fmt.Sprintf(format, v...),
+		Stack:   runtime2.Callers(1),
+	}
+}
+
+// Wrap create an error with cause
+func Wrap(cause error, code, msg string) *Error {
+	return &Error{
+		Code:    code,
+		Message: msg,
+		Cause:   cause,
+		Stack:   runtime2.Callers(1),
+	}
+}
+
+// With return a copy of error with the key/value field added
+func (e *Error) With(key string, value interface{}) *Error {
+	ne := *e
+	ne.Fields = make([]Field, len(e.Fields), len(e.Fields)+1)
+	copy(ne.Fields, e.Fields)
+	ne.Fields = append(ne.Fields, Field{Key: key, Value: value})
+
+	return &ne
+}
+
+// WithCause return a copy of error with the cause replaced
+func (e *Error) WithCause(cause error) *Error {
+	ne := *e
+	ne.Cause = cause
+
+	return &ne
+}
+
+// Field return value of the key, search from the latest field
+func (e *Error) Field(key string) (interface{}, bool) {
+	for i := len(e.Fields) - 1; i >= 0; i-- {
+		if e.Fields[i].Key == key {
+			return e.Fields[i].Value, true
+		}
+	}
+
+	return nil, false
+}
+
+// Error format as "code: message: cause", empty parts are omitted
+func (e *Error) Error() string {
+	s := e.Code
+	if e.Message != "" {
+		if s != "" {
+			s += ": "
+		}
+		s += e.Message
+	}
+	if e.Cause != nil {
+		if s != "" {
+			s += ": "
+		}
+		s += e.Cause.Error()
+	}
+
+	return s
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+func (e *Error) Is(target error) bool {
+	t, is := target.(*Error)
+
+	return is && t.Code != "" && t.Code == e.Code
+}
+
+// Format implements fmt.Formatter, %s and %v print the error message, %+v
+// also print fields, call stack and the cause chain
+func (e *Error) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			e.formatVerbose(s)
+			return
+		}
+		fallthrough
+	case 's':
+		io.WriteString(s, e.Error())
+	case 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	}
+}
+
+func (e *Error) formatVerbose(w io.Writer) {
+	if e.Code != "" {
+		io.WriteString(w, e.Code)
+		if e.Message != "" {
+			io.WriteString(w, ": ")
+		}
+	}
+	io.WriteString(w, e.Message)
+	for _, f := range e.Fields {
+		fmt.Fprintf(w, " %s=%+v", f.Key, f.Value)
+	}
+	if len(e.Stack) != 0 {
+		io.WriteString(w, "\n")
+		io.WriteString(w, e.Stack.String())
+	}
+	if e.Cause != nil {
+		fmt.Fprintf(w, "\ncaused by: %+v", e.Cause)
+	}
+}
+
+// Is is a wrapper of standard errors.Is
+func Is(err, target error) bool {
+	return stderrors.Is(err, target)
+}
+
+// As is a wrapper of standard errors.As
+func As(err error, target interface{}) bool {
+	return stderrors.As(err, target)
+}
+
+// Code return code of the first *Error in the chain, or empty string
+func Code(err error) string {
+	var e *Error
+	if As(err, &e) {
+		return e.Code
+	}
+
+	return ""
+}
+
+// Fields collect fields of all *Error in the chain, outer error first
+func Fields(err error) []Field {
+	var fields []Field
+	for err != nil {
+		if e, is := err.(*Error); is {
+			fields = append(fields, e.Fields...)
+		}
+		err = stderrors.Unwrap(err)
+	}
+
+	return fields
+}
@@ -0,0 +1,79 @@
+package errors_test
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/cosiner/gohper/errors"
+	"github.com/cosiner/gohper/errors/trace"
+	"github.com/cosiner/gohper/testing2"
+)
+
+var errNotFound = &errors.Error{Code: "not_found", Message: "resource not found"}
+
+func TestError(t *testing.T) {
+	tt := testing2.Wrap(t)
+
+	err := errors.Wrap(io.EOF, "read", "read config").With("file", "a.json").With("line", 3)
+	tt.Eq("read: read config: EOF", err.Error())
+	tt.True(stderrors.Is(err, io.EOF))
+	tt.Eq("read", errors.Code(err))
+	v, ok := err.Field("line")
+	tt.True(ok).Eq(3, v)
+
+	traced := trace.Trace(err)
+	tt.True(errors.Is(traced, io.EOF))
+	var e *errors.Error
+	tt.True(stderrors.As(traced, &e))
+	tt.Eq(err, e)
+	tt.Eq("read", errors.Code(traced))
+
+	nf := errNotFound.With("id", 1)
+	tt.True(errors.Is(trace.Trace(nf), errNotFound))
+	tt.False(errors.Is(err, errNotFound))
+	tt.Eq(0, len(errNotFound.Fields))
+
+	verbose := fmt.Sprintf("%+v", errors.NewError("code", "msg").With("k", "v").WithCause(err))
+	tt.True(strings.HasPrefix(verbose, "code: msg k=v\n"))
+	tt.True(strings.Contains(verbose, "errors_test.TestError"))
+	tt.True(strings.Contains(verbose, "caused by: read: read config file=a.json line=3\n"))
+	tt.Eq("code: msg", fmt.Sprintf("%v", errors.NewError("code", "msg")))
+	tt.Eq(2, len(errors.Fields(trace.Trace(err))))
+}
+
+func TestMultiError(t *testing.T) {
+	tt := testing2.Wrap(t)
+
+	var m errors.MultiError
+	tt.Nil(m.ErrorOrNil())
+	tt.Nil(errors.Combine(nil, nil))
+
+	inner := new(errors.MultiError).
+		AppendKey("name", errors.Err("empty")).
+		AppendKey("", errNotFound.With("id", 1))
+	m.Append(io.EOF, nil).AppendKey("user", inner)
+	var nilMulti *errors.MultiError
+	m.Append(nilMulti).AppendKey("nil", nilMulti)
+	tt.Eq(3, m.Len())
+	tt.Eq("user.name", m.Key(1))
+	tt.Eq("user", m.Key(2))
+	tt.Eq("3 errors: EOF; user.name: empty; user: not_found: resource not found", m.Error())
+
+	err := trace.Trace(m.ErrorOrNil())
+	tt.True(errors.Is(err, io.EOF))
+	tt.True(errors.Is(err, errNotFound))
+	var e *errors.Error
+	tt.True(errors.As(err, &e))
+	tt.Eq("not_found", e.Code)
+
+	data, err := json.Marshal(&m)
+	tt.Nil(err)
+	tt.Eq(`[{"error":"EOF"},{"key":"user.name","error":"empty"},{"key":"user","error":"not_found: resource not found","code":"not_found"}]`, string(data))
+
+	tt.Eq("EOF", errors.Combine(io.EOF).Error())
+	tt.Nil(errors.Combine(nilMulti, nil))
+}
@@ -0,0 +1,148 @@
+package errors
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+)
+
+// MultiError collect multiple errors with optional key for each one, nested
+// MultiError are flattened when appended. The zero value is ready to use.
+//
+// Is/As of standard errors package search every member by Unwrap.
+type MultiError struct {
+	keys []string
+	errs []error
+}
+
+// Append add errors without key, nil errors and nil *MultiError are ignored
+func (m *MultiError) Append(errs ...error) *MultiError {
+	for _, err := range errs {
+		m.AppendKey("", err)
+	}
+
+	return m
+}
+
+// AppendKey add error with key, if err is a MultiError, key is prefixed to
+// it's member's keys with a ".", nil err and nil *MultiError are ignored
+func (m *MultiError) AppendKey(key string, err error) *MultiError {
+	if err == nil {
+		return m
+	}
+
+	if me, is := err.(*MultiError); is {
+		if me == nil {
+			return m
+		}
+		for i, e := range me.errs {
+			m.keys = append(m.keys, joinKey(key, me.keys[i]))
+			m.errs = append(m.errs, e)
+		}
+	} else {
+		m.keys = append(m.keys, key)
+		m.errs = append(m.errs, err)
+	}
+
+	return m
+}
+
+func joinKey(parent, key string) string {
+	switch {
+	case parent == "":
+		return key
+	case key == "":
+		return parent
+	default:
+		return parent + "." + key
+	}
+}
+
+func (m *MultiError) Len() int {
+	if m == nil {
+		return 0
+	}
+
+	return len(m.errs)
+}
+
+func (m *MultiError) Errors() []error {
+	if m == nil {
+		return nil
+	}
+
+	return m.errs
+}
+
+// Key return key of the i-th error
+func (m *MultiError) Key(i int) string {
+	return m.keys[i]
+}
+
+// ErrorOrNil return nil if there is no error, otherwise the MultiError itself
+func (m *MultiError) ErrorOrNil() error {
+	if m.Len() == 0 {
+		return nil
+	}
+
+	return m
+}
+
+func (m *MultiError) item(i int) string {
+	if m.keys[i] == "" {
+		return m.errs[i].Error()
+	}
+
+	return m.keys[i] + ": " + m.errs[i].Error()
+}
+
+// Error format as "n errors: key1: err1; err2"
+func (m *MultiError) Error() string {
+	switch n := m.Len(); n {
+	case 0:
+		return "no error"
+	case 1:
+		return m.item(0)
+	default:
+		buf := bytes.NewBufferString(strconv.Itoa(n))
+		buf.WriteString(" errors: ")
+		for i := 0; i < n; i++ {
+			if i != 0 {
+				buf.WriteString("; ")
+			}
+			buf.WriteString(m.item(i))
+		}
+
+		return buf.String()
+	}
+}
+
+func (m *MultiError) Unwrap() []error {
+	return m.Errors()
+}
+
+type jsonError struct {
+	Key   string `json:"key,omitempty"`
+	Error string `json:"error"`
+	Code  string `json:"code,omitempty"`
+}
+
+// MarshalJSON render errors as an array of {"key", "error", "code"} objects,
+// code is set if there is a *Error in the error chain
+func (m *MultiError) MarshalJSON() ([]byte, error) {
+	items := make([]jsonError, m.Len())
+	for i := range items {
+		items[i] = jsonError{
+			Key:   m.keys[i],
+			Error: m.errs[i].Error(),
+			Code:  Code(m.errs[i]),
+		}
+	}
+
+	return json.Marshal(items)
+}
+
+// Combine errors to a MultiError, return nil if all errors are nil
+func Combine(errs ...error) error {
+	return new(MultiError).Append(errs...).ErrorOrNil()
+}
@@ -2,46 +2,130 @@ package trace
 
 import (
 	"fmt"
+	"io"
+	"log"
 
 	"github.com/cosiner/gohper/runtime2"
 )
 
-var TraceEnabled = true
+var (
+	TraceEnabled = true
+	// FullStack make the first trace point capture the whole call stack,
+	// only program counters are recorded, they are symbolized when formatting
+	FullStack = false
+	// Logger is used to log errors when trace is disabled, nil means don't log
+	Logger func(...interface{}) = log.Println
+)
 
 type traceError struct {
-	pos string
-	err error
+	// trail is the positions the error passed by, the first one is where it's
+	// traced first time
+	trail []string
+	stack runtime2.CallStack
+	err   error
+}
+
+// Pos return the first trace position
+func (e *traceError) Pos() string {
+	return e.trail[0]
 }
 
-func (e traceError) Pos() string {
-	return e.pos
+// Trail return all trace positions in order
+func (e *traceError) Trail() []string {
+	return e.trail
 }
-func (e traceError) Error() string {
-	return e.pos + ":" + e.err.Error()
+
+func (e *traceError) Stack() runtime2.CallStack {
+	return e.stack
+}
+
+func (e *traceError) Error() string {
+	return e.trail[0] + ":" + e.err.Error()
 }
 
-func (e traceError) Unwrap() error {
+func (e *traceError) Unwrap() error {
 	return e.err
 }
 
+// Format implements fmt.Formatter, %+v print the trail and call stack
+func (e *traceError) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			fmt.Fprintf(s, "%+v", e.err)
+			for _, pos := range e.trail {
+				io.WriteString(s, "\n\tat ")
+				io.WriteString(s, pos)
+			}
+			if len(e.stack) != 0 {
+				io.WriteString(s, "\n")
+				io.WriteString(s, e.stack.String())
+			}
+			return
+		}
+		fallthrough
+	case 's':
+		io.WriteString(s, e.Error())
+	case 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	}
+}
+
+// TraceDepth record the caller position of given depth, if err is already
+// traced, the position is appended to it's trail
 func TraceDepth(err error, depth int) error {
 	if err == nil {
 		return nil
 	}
 	if !TraceEnabled {
-		fmt.Println(err)
+		if Logger != nil {
+			Logger(err)
+		}
 		return err
 	}
 
-	if _, is := err.(traceError); is {
-		return err
+	pos := runtime2.Caller(depth + 1)
+	if te, is := err.(*traceError); is {
+		trail := make([]string, len(te.trail), len(te.trail)+1)
+		copy(trail, te.trail)
+
+		return &traceError{
+			trail: append(trail, pos),
+			stack: te.stack,
+			err:   te.err,
+		}
 	}
-	return traceError{
-		pos: runtime2.Caller(depth + 1),
-		err: err,
+
+	te := &traceError{
+		trail: []string{pos},
+		err:   err,
+	}
+	if FullStack {
+		te.stack = runtime2.Callers(depth + 1)
 	}
+
+	return te
 }
 
 func Trace(err error) error {
 	return TraceDepth(err, 1)
 }
+
+// Trail return trace positions of err, nil if it's not traced
+func Trail(err error) []string {
+	if te, is := err.(*traceError); is {
+		return te.trail
+	}
+
+	return nil
+}
+
+// Stack return the call stack captured at the first trace point, nil if it's
+// not traced or FullStack is disabled at that time
+func Stack(err error) runtime2.CallStack {
+	if te, is := err.(*traceError); is {
+		return te.stack
+	}
+
+	return nil
+}
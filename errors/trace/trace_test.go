@@ -1,6 +1,9 @@
 package trace
 
 import (
+	"fmt"
+	"log"
+	"strings"
 	"testing"
 
 	"github.com/cosiner/gohper/errors"
@@ -14,7 +17,7 @@ func TestTrace(t *testing.T) {
 	var e error = errors.Err("Error")
 
 	e2 := Trace(e)
-	es := "trace/trace_test.go:16:" + e.Error()
+	es := "trace/trace_test.go:19:" + e.Error()
 	tt.Eq(es, e2.Error())
 
 	e2 = Trace(e2)
@@ -24,3 +27,34 @@ func TestTrace(t *testing.T) {
 	e2 = Trace(e)
 	tt.Eq(e2, e)
 }
+
+func TestTrail(t *testing.T) {
+	tt := testing2.Wrap(t)
+
+	TraceEnabled = true
+	FullStack = true
+	defer func() {
+		FullStack = false
+	}()
+
+	e := Trace(errors.Err("Error"))
+	e = Trace(e)
+	tt.DeepEq([]string{"trace/trace_test.go:40", "trace/trace_test.go:41"}, Trail(e))
+	tt.True(strings.Contains(Stack(e).String(), "trace.TestTrail"))
+	tt.True(strings.HasPrefix(fmt.Sprintf("%+v", e), "Error\n\tat trace/trace_test.go:40\n\tat trace/trace_test.go:41\n"))
+	tt.Eq("trace/trace_test.go:40:Error", fmt.Sprint(e))
+
+	var logged []interface{}
+	Logger = func(v ...interface{}) {
+		logged = v
+	}
+	TraceEnabled = false
+	defer func() {
+		TraceEnabled = true
+		Logger = log.Println
+	}()
+	err := errors.Err("disabled")
+	tt.Eq(err, Trace(err))
+	tt.DeepEq([]interface{}{err}, logged)
+	tt.Nil(Trail(err))
+}
@@ -68,3 +68,11 @@ func TestPath(t *testing.T) {
 
 	PackagePath("bufio") // difficult to test
 }
+
+func TestGoFiles(t *testing.T) {
+	tt := testing2.Wrap(t)
+
+	files, err := GoFiles(".", false)
+	tt.Nil(err)
+	tt.DeepEq([]string{"field.go", "filetype.go", "format.go", "package.go"}, files)
+}
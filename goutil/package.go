@@ -39,3 +39,21 @@ func PackagePath(pkgName string) string {
 func WriteImportpath(w io.Writer, path ...string) (int, error) {
 	return fmt.Fprintf(w, `"%s"`+"\n", strings.Join(path, "/"))
 }
+
+// GoFiles return go files under root in lexical order, directories ignored
+// by .gitignore and the vendor directory are skipped
+func GoFiles(root string, withTest bool) ([]string, error) {
+	var files []string
+	err := path2.Walker{
+		IgnoreFiles: []string{".gitignore"},
+		Ignore:      path2.NewIgnore(".git/", "vendor/"),
+	}.Walk(root, func(e path2.WalkEntry) error {
+		if IsGoFile(e.Path) && (withTest || !IsTestFile(e.Path)) {
+			files = append(files, e.Path)
+		}
+
+		return nil
+	})
+
+	return files, err
+}
@@ -0,0 +1,157 @@
+package log2
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cosiner/gohper/bytes2"
+	"github.com/cosiner/gohper/errors"
+)
+
+const ErrWriterClosed = errors.Err("writer already closed")
+
+// AsyncWriter write data to underlying writer in a background goroutine,
+// data is copied to buffers allocated from Pool.
+type AsyncWriter struct {
+	w    io.Writer
+	pool bytes2.Pool
+	drop bool
+
+	ch      chan []byte
+	flushCh chan chan error
+	done    chan struct{}
+	dropped uint64
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewAsyncWriter create an async writer with given queue size, if drop is
+// true, data is dropped instead of blocking when queue is full, a nil pool
+// use a SyncPool
+func NewAsyncWriter(w io.Writer, size int, pool bytes2.Pool, drop bool) *AsyncWriter {
+	if size <= 0 {
+		size = 1024
+	}
+	if pool == nil {
+		pool = bytes2.NewSyncPool(0, true)
+	}
+
+	a := &AsyncWriter{
+		w:       w,
+		pool:    pool,
+		drop:    drop,
+		ch:      make(chan []byte, size),
+		flushCh: make(chan chan error),
+		done:    make(chan struct{}),
+	}
+	go a.loop()
+
+	return a
+}
+
+func (a *AsyncWriter) loop() {
+	defer close(a.done)
+
+	var err error
+	for {
+		select {
+		case buf, ok := <-a.ch:
+			if !ok {
+				return
+			}
+			if _, e := a.w.Write(buf); e != nil && err == nil {
+				err = e
+			}
+			a.pool.Put(buf)
+		case c := <-a.flushCh:
+			// drain queued data before reporting
+			for n := len(a.ch); n > 0; n-- {
+				buf := <-a.ch
+				if _, e := a.w.Write(buf); e != nil && err == nil {
+					err = e
+				}
+				a.pool.Put(buf)
+			}
+			if f, is := a.w.(Flusher); is && err == nil {
+				err = f.Flush()
+			}
+			c <- err
+			err = nil
+		}
+	}
+}
+
+// Write queue a copy of data, the write error of underlying writer is
+// reported by Flush
+func (a *AsyncWriter) Write(data []byte) (int, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		return 0, ErrWriterClosed
+	}
+
+	buf := append(a.pool.Get(len(data), false), data...)
+	if !a.drop {
+		a.ch <- buf
+		return len(data), nil
+	}
+
+	select {
+	case a.ch <- buf:
+	default:
+		a.pool.Put(buf)
+		atomic.AddUint64(&a.dropped, 1)
+	}
+	return len(data), nil
+}
+
+// Dropped return count of dropped writes
+func (a *AsyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&a.dropped)
+}
+
+// Flush wait all queued data written, return the first write error since
+// last flush
+func (a *AsyncWriter) Flush() error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		return ErrWriterClosed
+	}
+
+	c := make(chan error)
+	a.flushCh <- c
+	return <-c
+}
+
+// Close flush queued data and stop the background goroutine, the underlying
+// writer is closed if it's an io.Closer
+func (a *AsyncWriter) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return ErrWriterClosed
+	}
+	a.mu.Unlock()
+
+	err := a.Flush()
+
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return ErrWriterClosed
+	}
+	a.closed = true
+	close(a.ch)
+	a.mu.Unlock()
+	<-a.done
+
+	if c, is := a.w.(io.Closer); is {
+		if e := c.Close(); err == nil {
+			err = e
+		}
+	}
+	return err
+}
@@ -0,0 +1,276 @@
+package log2
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cosiner/gohper/terminal/color"
+)
+
+// JSONEncoder encode entry as a json object in one line, keys "time", "level",
+// "msg", "caller" are followed by fields in order
+type JSONEncoder struct {
+	// TimeLayout default time.RFC3339Nano
+	TimeLayout string
+}
+
+func (j *JSONEncoder) Encode(dst []byte, e *Entry) []byte {
+	layout := j.TimeLayout
+	if layout == "" {
+		layout = time.RFC3339Nano
+	}
+
+	dst = append(dst, `{"time":"`...)
+	dst = e.Time.AppendFormat(dst, layout)
+	dst = append(dst, `","level":"`...)
+	dst = append(dst, e.Level.String()...)
+	dst = append(dst, `","msg":`...)
+	dst = appendJSONString(dst, e.Msg)
+	if e.Caller != "" {
+		dst = append(dst, `,"caller":`...)
+		dst = appendJSONString(dst, e.Caller)
+	}
+	for _, f := range e.Fields {
+		dst = append(dst, ',')
+		dst = appendJSONString(dst, f.Key)
+		dst = append(dst, ':')
+		dst = appendJSONValue(dst, f.Value)
+	}
+
+	return append(dst, "}\n"...)
+}
+
+const hex = "0123456789abcdef"
+
+func appendJSONString(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		b := s[i]
+		if b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' {
+				i++
+				continue
+			}
+
+			dst = append(dst, s[start:i]...)
+			switch b {
+			case '"', '\\':
+				dst = append(dst, '\\', b)
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\r':
+				dst = append(dst, '\\', 'r')
+			case '\t':
+				dst = append(dst, '\\', 't')
+			default:
+				dst = append(dst, '\\', 'u', '0', '0', hex[b>>4], hex[b&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, `�`...)
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+
+	dst = append(dst, s[start:]...)
+	return append(dst, '"')
+}
+
+func appendFloat(dst []byte, f float64, bits int, quoteSpecial bool) []byte {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		s := strconv.FormatFloat(f, 'g', -1, bits)
+		if quoteSpecial {
+			return append(append(append(dst, '"'), s...), '"')
+		}
+		return append(dst, s...)
+	}
+
+	return strconv.AppendFloat(dst, f, 'g', -1, bits)
+}
+
+func appendJSONValue(dst []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(dst, "null"...)
+	case string:
+		return appendJSONString(dst, v)
+	case []byte:
+		return appendJSONString(dst, string(v))
+	case bool:
+		return strconv.AppendBool(dst, v)
+	case int:
+		return strconv.AppendInt(dst, int64(v), 10)
+	case int8:
+		return strconv.AppendInt(dst, int64(v), 10)
+	case int16:
+		return strconv.AppendInt(dst, int64(v), 10)
+	case int32:
+		return strconv.AppendInt(dst, int64(v), 10)
+	case int64:
+		return strconv.AppendInt(dst, v, 10)
+	case uint:
+		return strconv.AppendUint(dst, uint64(v), 10)
+	case uint8:
+		return strconv.AppendUint(dst, uint64(v), 10)
+	case uint16:
+		return strconv.AppendUint(dst, uint64(v), 10)
+	case uint32:
+		return strconv.AppendUint(dst, uint64(v), 10)
+	case uint64:
+		return strconv.AppendUint(dst, v, 10)
+	case float32:
+		return appendFloat(dst, float64(v), 32, true)
+	case float64:
+		return appendFloat(dst, v, 64, true)
+	case time.Time:
+		return appendJSONString(dst, v.Format(time.RFC3339Nano))
+	case time.Duration:
+		return appendJSONString(dst, v.String())
+	}
+
+	// methods may panic on nil pointer
+	if isNilPointer(v) {
+		return append(dst, "null"...)
+	}
+	switch v := v.(type) {
+	case error:
+		return appendJSONString(dst, v.Error())
+	case json.Marshaler:
+		if data, err := v.MarshalJSON(); err == nil {
+			return append(dst, data...)
+		}
+	case fmt.Stringer:
+		return appendJSONString(dst, v.String())
+	}
+
+	if data, err := json.Marshal(v); err == nil {
+		return append(dst, data...)
+	}
+	return appendJSONString(dst, fmt.Sprint(v))
+}
+
+// ConsoleEncoder encode entry as human readable text:
+//
+//	time LEVEL caller msg key=value key="quoted value"
+type ConsoleEncoder struct {
+	// TimeLayout default "2006-01-02 15:04:05.000"
+	TimeLayout string
+	// Color render level names with Colors
+	Color bool
+	// Colors is renderers for each level, default LevelColors
+	Colors map[Level]*color.Renderer
+}
+
+// LevelColors is the default level colors of ConsoleEncoder
+var LevelColors = map[Level]*color.Renderer{
+	LevelDebug: color.Blue,
+	LevelInfo:  color.Green,
+	LevelWarn:  color.Yellow,
+	LevelError: color.Red,
+	LevelFatal: color.Magenta,
+}
+
+var levelTags = [...]string{
+	LevelDebug: "DEBUG",
+	LevelInfo:  "INFO ",
+	LevelWarn:  "WARN ",
+	LevelError: "ERROR",
+	LevelFatal: "FATAL",
+}
+
+func (c *ConsoleEncoder) Encode(dst []byte, e *Entry) []byte {
+	layout := c.TimeLayout
+	if layout == "" {
+		layout = "2006-01-02 15:04:05.000"
+	}
+	dst = e.Time.AppendFormat(dst, layout)
+	dst = append(dst, ' ')
+
+	tag := "?????"
+	if e.Level >= LevelDebug && e.Level <= LevelFatal {
+		tag = levelTags[e.Level]
+	}
+	if c.Color {
+		colors := c.Colors
+		if colors == nil {
+			colors = LevelColors
+		}
+		if r := colors[e.Level]; r != nil {
+			tag = r.RenderString(tag)
+		}
+	}
+	dst = append(dst, tag...)
+
+	if e.Caller != "" {
+		dst = append(dst, ' ')
+		dst = append(dst, e.Caller...)
+	}
+	dst = append(dst, ' ')
+	dst = append(dst, e.Msg...)
+	for _, f := range e.Fields {
+		dst = append(dst, ' ')
+		dst = append(dst, f.Key...)
+		dst = append(dst, '=')
+		dst = appendConsoleValue(dst, f.Value)
+	}
+
+	return append(dst, '\n')
+}
+
+func isNilPointer(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
+
+func appendConsoleValue(dst []byte, v interface{}) []byte {
+	var s string
+	switch v := v.(type) {
+	case string:
+		s = v
+	case error:
+		if isNilPointer(v) {
+			s = "<nil>"
+		} else {
+			s = v.Error()
+		}
+	case float32:
+		return appendFloat(dst, float64(v), 32, false)
+	case float64:
+		return appendFloat(dst, v, 64, false)
+	default:
+		s = fmt.Sprint(v)
+	}
+
+	if needQuote(s) {
+		return strconv.AppendQuote(dst, s)
+	}
+	return append(dst, s...)
+}
+
+func needQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '"' || r == '=' || r == 0x7f || r == utf8.RuneError {
+			return true
+		}
+	}
+
+	return false
+}
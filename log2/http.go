@@ -0,0 +1,28 @@
+package log2
+
+import "net/http"
+
+// coder is error with a status code, such as httperrs.Error
+type coder interface {
+	Code() int
+}
+
+// HTTPErrorLogger return a function log errors of http requests at LevelError
+// with method and path, it can be used as httperrs.Middleware.Logger. If
+// requestID is not nil, it's result is logged as "request_id". If err has
+// a Code() int method, such as httperrs.Error, the code is logged as "status".
+func (l *Logger) HTTPErrorLogger(requestID func(*http.Request) string) func(*http.Request, error) {
+	return func(r *http.Request, err error) {
+		kv := []interface{}{
+			"method", r.Method,
+			"path", r.URL.Path,
+		}
+		if requestID != nil {
+			kv = append(kv, "request_id", requestID(r))
+		}
+		if e, is := err.(coder); is && !isNilPointer(e) {
+			kv = append(kv, "status", e.Code())
+		}
+		l.log(1, LevelError, "http request failed", append(kv, "error", err))
+	}
+}
@@ -0,0 +1,54 @@
+package log2
+
+import (
+	"strings"
+
+	"github.com/cosiner/gohper/errors"
+)
+
+type Level int32
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelFatal
+	// LevelOff disable all logs
+	LevelOff
+)
+
+const ErrUnknownLevel = errors.Err("unknown log level")
+
+var levelNames = [...]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+	LevelFatal: "fatal",
+	LevelOff:   "off",
+}
+
+func (l Level) String() string {
+	if l < LevelDebug || l > LevelOff {
+		return "unknown"
+	}
+
+	return levelNames[l]
+}
+
+// ParseLevel parse level name case-insensitively, "warning" is same as "warn"
+func ParseLevel(s string) (Level, error) {
+	s = strings.ToLower(s)
+	if s == "warning" {
+		return LevelWarn, nil
+	}
+
+	for l, name := range levelNames {
+		if name == s {
+			return Level(l), nil
+		}
+	}
+
+	return LevelOff, ErrUnknownLevel
+}
@@ -0,0 +1,193 @@
+package log2
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cosiner/gohper/errors"
+	"github.com/cosiner/gohper/testing2"
+)
+
+func TestLevel(t *testing.T) {
+	tt := testing2.Wrap(t)
+
+	l, err := ParseLevel("WARNING")
+	tt.Nil(err).Eq(LevelWarn, l)
+	l, err = ParseLevel("error")
+	tt.Nil(err).Eq(LevelError, l)
+	_, err = ParseLevel("verbose")
+	tt.Eq(ErrUnknownLevel, err)
+	tt.Eq("info", LevelInfo.String())
+}
+
+func TestJSONLogger(t *testing.T) {
+	tt := testing2.Wrap(t)
+
+	var buf bytes.Buffer
+	log := New(Config{Level: LevelInfo, Output: &buf, Caller: true})
+	log.Debug("ignored")
+	log.With("app", "test").Info("hello\n\"world\"", "n", 1, "err", errors.Err("bad"), "f", math.NaN(), "odd")
+
+	line := buf.String()
+	tt.True(strings.HasSuffix(line, "}\n"))
+	var m map[string]interface{}
+	tt.Nil(json.Unmarshal([]byte(line), &m))
+	tt.Eq("info", m["level"])
+	tt.Eq("hello\n\"world\"", m["msg"])
+	tt.Eq("test", m["app"])
+	tt.Eq(1.0, m["n"])
+	tt.Eq("bad", m["err"])
+	tt.Eq("NaN", m["f"])
+	tt.Nil(m["odd"])
+	tt.True(strings.HasPrefix(m["caller"].(string), "log2/log_test.go:"))
+	tt.True(strings.Index(line, `"app"`) < strings.Index(line, `"n"`))
+
+	buf.Reset()
+	var nilErr *os.PathError
+	log.Info("nil", "err", error(nilErr), "ptr", (*time.Time)(nil))
+	m = nil
+	tt.Nil(json.Unmarshal(buf.Bytes(), &m))
+	v, has := m["err"]
+	tt.True(has)
+	tt.Nil(v)
+	tt.Nil(m["ptr"])
+
+	buf.Reset()
+	r := httptest.NewRequest("GET", "/a?b=c", nil)
+	log.HTTPErrorLogger(func(*http.Request) string { return "id" })(r, httpError(502))
+	m = nil
+	tt.Nil(json.Unmarshal(buf.Bytes(), &m))
+	tt.Eq("/a", m["path"])
+	tt.Eq("id", m["request_id"])
+	tt.Eq(502.0, m["status"])
+	tt.Eq("bad gateway", m["error"])
+
+	buf.Reset()
+	log.SetLevel(LevelOff)
+	log.Error("ignored")
+	tt.Eq(0, buf.Len())
+}
+
+type httpError int
+
+func (e httpError) Error() string { return strings.ToLower(http.StatusText(int(e))) }
+func (e httpError) Code() int     { return int(e) }
+
+func TestConsoleEncoder(t *testing.T) {
+	tt := testing2.Wrap(t)
+
+	e := &Entry{
+		Time:   time.Date(2015, 1, 2, 3, 4, 5, 0, time.UTC),
+		Level:  LevelWarn,
+		Msg:    "disk full",
+		Fields: []errors.Field{{Key: "path", Value: "/data"}, {Key: "reason", Value: "no space"}},
+	}
+	enc := &ConsoleEncoder{}
+	tt.Eq("2015-01-02 03:04:05.000 WARN  disk full path=/data reason=\"no space\"\n", string(enc.Encode(nil, e)))
+
+	var nilErr *os.PathError
+	e.Fields = []errors.Field{{Key: "err", Value: error(nilErr)}}
+	tt.Eq("2015-01-02 03:04:05.000 WARN  disk full err=<nil>\n", string(enc.Encode(nil, e)))
+	e.Fields = []errors.Field{{Key: "path", Value: "/data"}, {Key: "reason", Value: "no space"}}
+
+	enc.Color = true
+	tt.True(strings.Contains(string(enc.Encode(nil, e)), LevelColors[LevelWarn].RenderString("WARN ")))
+}
+
+func TestPrinter(t *testing.T) {
+	tt := testing2.Wrap(t)
+
+	var buf bytes.Buffer
+	log := New(Config{Output: &buf, Encoder: &ConsoleEncoder{TimeLayout: "-"}})
+	log.Printer(LevelError)("a", 1)
+	tt.Eq("- ERROR a 1\n", buf.String())
+}
+
+func TestSampler(t *testing.T) {
+	tt := testing2.Wrap(t)
+
+	s := NewSampler(time.Hour, 2, 3)
+	var allowed []int
+	for i := 1; i <= 10; i++ {
+		if s.Allow(LevelInfo, "msg") {
+			allowed = append(allowed, i)
+		}
+	}
+	tt.DeepEq([]int{1, 2, 5, 8}, allowed)
+	tt.True(s.Allow(LevelInfo, "other"))
+	tt.True(s.Allow(LevelError, "msg"))
+}
+
+func TestAsyncWriter(t *testing.T) {
+	tt := testing2.Wrap(t)
+
+	var buf bytes.Buffer
+	w := NewAsyncWriter(&buf, 4, nil, false)
+	log := New(Config{Output: w, Encoder: &ConsoleEncoder{TimeLayout: "-"}})
+	for i := 0; i < 100; i++ {
+		log.Info("msg", "i", i)
+	}
+	tt.Nil(log.Flush())
+	tt.Eq(100, strings.Count(buf.String(), "\n"))
+	tt.True(strings.HasSuffix(buf.String(), "i=99\n"))
+
+	tt.Nil(w.Close())
+	_, err := w.Write([]byte("a"))
+	tt.Eq(ErrWriterClosed, err)
+}
+
+func TestRotateWriter(t *testing.T) {
+	tt := testing2.Wrap(t)
+
+	dir, err := ioutil.TempDir("", "log2")
+	tt.Nil(err)
+	defer os.RemoveAll(dir)
+
+	w := &RotateWriter{
+		Filename:   filepath.Join(dir, "app.log"),
+		MaxSize:    10,
+		MaxBackups: 2,
+		Compress:   true,
+	}
+	for i := 0; i < 5; i++ {
+		_, err = w.Write([]byte("12345678\n"))
+		tt.Nil(err)
+		time.Sleep(2 * time.Millisecond)
+	}
+	tt.Nil(w.Close())
+
+	backups, err := w.Backups()
+	tt.Nil(err).Eq(2, len(backups))
+	for _, b := range backups {
+		tt.True(strings.HasSuffix(b, ".gz"))
+		fd, err := os.Open(b)
+		tt.Nil(err)
+		gz, err := gzip.NewReader(fd)
+		tt.Nil(err)
+		data, err := ioutil.ReadAll(gz)
+		tt.Nil(err).Eq("12345678\n", string(data))
+		fd.Close()
+	}
+
+	data, err := ioutil.ReadFile(w.Filename)
+	tt.Nil(err).Eq("12345678\n", string(data))
+}
+
+func TestNextRotate(t *testing.T) {
+	tt := testing2.Wrap(t)
+
+	loc := time.FixedZone("UTC+8", 8*3600)
+	w := &RotateWriter{Interval: 24 * time.Hour, Location: loc}
+	next := w.nextRotate(time.Date(2015, 1, 2, 23, 0, 0, 0, loc))
+	tt.True(next.Equal(time.Date(2015, 1, 3, 0, 0, 0, 0, loc)))
+}
@@ -0,0 +1,207 @@
+package log2
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cosiner/gohper/bytes2"
+	"github.com/cosiner/gohper/errors"
+	"github.com/cosiner/gohper/runtime2"
+)
+
+// Entry is a log record passed to Encoder
+type Entry struct {
+	Time   time.Time
+	Level  Level
+	Msg    string
+	Caller string
+	Fields []errors.Field
+}
+
+// Encoder append encoded entry to dst, the result must end with a newline
+type Encoder interface {
+	Encode(dst []byte, e *Entry) []byte
+}
+
+// Flusher is implemented by outputs which buffer data, such as AsyncWriter
+type Flusher interface {
+	Flush() error
+}
+
+type Config struct {
+	Level Level
+	// Encoder default JSONEncoder
+	Encoder Encoder
+	// Output default os.Stderr
+	Output io.Writer
+	// Caller record the position where log is called
+	Caller bool
+	// Sampler drop logs if not nil
+	Sampler *Sampler
+	// Pool for encode buffers, default a SyncPool with 1024 bytes buffers
+	Pool bytes2.Pool
+}
+
+type core struct {
+	level   int32
+	encoder Encoder
+	out     io.Writer
+	caller  bool
+	sampler *Sampler
+	pool    bytes2.Pool
+
+	mu sync.Mutex // serialize writes
+}
+
+// Logger is a leveled logger with key/value fields, it's safe for concurrent
+// use, loggers created by With share the same output and level
+type Logger struct {
+	core   *core
+	fields []errors.Field
+}
+
+func New(c Config) *Logger {
+	if c.Encoder == nil {
+		c.Encoder = &JSONEncoder{}
+	}
+	if c.Output == nil {
+		c.Output = os.Stderr
+	}
+	if c.Pool == nil {
+		c.Pool = bytes2.NewSyncPool(0, true)
+	}
+
+	return &Logger{
+		core: &core{
+			level:   int32(c.Level),
+			encoder: c.Encoder,
+			out:     c.Output,
+			caller:  c.Caller,
+			sampler: c.Sampler,
+			pool:    c.Pool,
+		},
+	}
+}
+
+func (l *Logger) Level() Level {
+	return Level(atomic.LoadInt32(&l.core.level))
+}
+
+// SetLevel change level of the logger and all loggers share it's output
+func (l *Logger) SetLevel(level Level) {
+	atomic.StoreInt32(&l.core.level, int32(level))
+}
+
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.Level() && level < LevelOff
+}
+
+// With return a logger with key/value pairs added to every entry, a key
+// without value is paired with nil
+func (l *Logger) With(kv ...interface{}) *Logger {
+	fields := make([]errors.Field, len(l.fields), len(l.fields)+len(kv)/2+1)
+	copy(fields, l.fields)
+
+	return &Logger{
+		core:   l.core,
+		fields: appendFields(fields, kv),
+	}
+}
+
+func appendFields(fields []errors.Field, kv []interface{}) []errors.Field {
+	for i := 0; i < len(kv); i += 2 {
+		f := errors.Field{Key: fmt.Sprint(kv[i])}
+		if i+1 < len(kv) {
+			f.Value = kv[i+1]
+		}
+		fields = append(fields, f)
+	}
+
+	return fields
+}
+
+func (l *Logger) Debug(msg string, kv ...interface{}) {
+	l.log(1, LevelDebug, msg, kv)
+}
+
+func (l *Logger) Info(msg string, kv ...interface{}) {
+	l.log(1, LevelInfo, msg, kv)
+}
+
+func (l *Logger) Warn(msg string, kv ...interface{}) {
+	l.log(1, LevelWarn, msg, kv)
+}
+
+func (l *Logger) Error(msg string, kv ...interface{}) {
+	l.log(1, LevelError, msg, kv)
+}
+
+// Fatal log and exit program with code 1, the output is flushed before exit
+func (l *Logger) Fatal(msg string, kv ...interface{}) {
+	l.log(1, LevelFatal, msg, kv)
+	l.Flush()
+	os.Exit(1)
+}
+
+// Log at given level, depth is the caller depth used when Caller is enabled,
+// 0 means the caller of Log
+func (l *Logger) Log(depth int, level Level, msg string, kv ...interface{}) {
+	l.log(depth+1, level, msg, kv)
+}
+
+func (l *Logger) log(depth int, level Level, msg string, kv []interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	c := l.core
+	if c.sampler != nil && !c.sampler.Allow(level, msg) {
+		return
+	}
+
+	e := Entry{
+		Time:   time.Now(),
+		Level:  level,
+		Msg:    msg,
+		Fields: l.fields,
+	}
+	if len(kv) != 0 {
+		e.Fields = appendFields(e.Fields[:len(e.Fields):len(e.Fields)], kv)
+	}
+	if c.caller {
+		e.Caller = runtime2.Caller(depth + 1)
+	}
+
+	buf := c.encoder.Encode(c.pool.Get(bytes2.DEF_BUFSIZE, false), &e)
+	c.mu.Lock()
+	c.out.Write(buf)
+	c.mu.Unlock()
+	c.pool.Put(buf)
+}
+
+// Flush output if it implements Flusher
+func (l *Logger) Flush() error {
+	if f, is := l.core.out.(Flusher); is {
+		return f.Flush()
+	}
+
+	return nil
+}
+
+// Printer return a function log arguments joined by space at given level, it
+// can be used as callback of runtime2.Recover, trace.Logger, etc..
+func (l *Logger) Printer(level Level) func(...interface{}) {
+	return func(v ...interface{}) {
+		if l.Enabled(level) {
+			l.log(1, level, sprint(v), nil)
+		}
+	}
+}
+
+func sprint(v []interface{}) string {
+	s := fmt.Sprintln(v...)
+	return s[:len(s)-1]
+}
@@ -0,0 +1,242 @@
+package log2
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cosiner/gohper/os2/file"
+)
+
+// BackupTimeLayout is the time format of rotated file suffix, it's sortable
+const BackupTimeLayout = "20060102-150405.000"
+
+// RotateWriter write to a file, rotate it when the size exceeds MaxSize or
+// a new Interval begins. Rotated files are renamed to "name.<time>" and
+// optionally compressed to "name.<time>.gz" in background.
+type RotateWriter struct {
+	Filename string
+	// MaxSize in bytes, 0 means don't rotate by size
+	MaxSize int64
+	// Interval rotate when time crosses a multiple of Interval in Location,
+	// e.g. 24 * time.Hour rotate at midnight, 0 means don't rotate by time
+	Interval time.Duration
+	// Location for Interval, default time.Local
+	Location *time.Location
+	// MaxBackups is the max count of rotated files to keep, 0 keep all
+	MaxBackups int
+	Compress   bool
+
+	mu   sync.Mutex
+	fd   *os.File
+	size int64
+	next time.Time
+
+	bg sync.Mutex // serialize background compress and cleanup
+	wg sync.WaitGroup
+}
+
+func (w *RotateWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.fd == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	now := time.Now()
+	if (w.Interval > 0 && !now.Before(w.next)) ||
+		(w.MaxSize > 0 && w.size > 0 && w.size+int64(len(data)) > w.MaxSize) {
+		if err := w.rotate(now); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.fd.Write(data)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *RotateWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.Filename), file.DirPerm); err != nil {
+		return err
+	}
+	fd, err := os.OpenFile(w.Filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, file.FilePerm)
+	if err != nil {
+		return err
+	}
+	info, err := fd.Stat()
+	if err != nil {
+		fd.Close()
+		return err
+	}
+
+	w.fd = fd
+	w.size = info.Size()
+	w.next = w.nextRotate(time.Now())
+	return nil
+}
+
+func (w *RotateWriter) nextRotate(now time.Time) time.Time {
+	if w.Interval <= 0 {
+		return time.Time{}
+	}
+
+	loc := w.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	now = now.In(loc)
+	_, offset := now.Zone()
+	shift := time.Duration(offset) * time.Second
+	// truncate in local wall clock instead of UTC
+	return now.Add(shift).Truncate(w.Interval).Add(w.Interval - shift)
+}
+
+// Rotate force a rotation
+func (w *RotateWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.fd == nil {
+		if err := w.open(); err != nil {
+			return err
+		}
+	}
+	return w.rotate(time.Now())
+}
+
+func (w *RotateWriter) rotate(now time.Time) error {
+	if err := w.fd.Close(); err != nil {
+		return err
+	}
+	w.fd = nil
+
+	backup := w.Filename + "." + now.Format(BackupTimeLayout)
+	for i := 1; file.IsExist(backup) || file.IsExist(backup+".gz"); i++ {
+		backup = w.Filename + "." + now.Format(BackupTimeLayout) + "-" + strconv.Itoa(i)
+	}
+	if err := os.Rename(w.Filename, backup); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.bg.Lock()
+		defer w.bg.Unlock()
+
+		if w.Compress {
+			compressFile(backup)
+		}
+		w.cleanup()
+	}()
+	return nil
+}
+
+func compressFile(fname string) error {
+	src, err := os.Open(fname)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp := fname + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, file.FilePerm)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	_, err = io.Copy(gz, src)
+	if e := gz.Close(); err == nil {
+		err = e
+	}
+	if e := dst.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Rename(tmp, fname+".gz")
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.Remove(fname)
+}
+
+// Backups return rotated files sorted from oldest to newest
+func (w *RotateWriter) Backups() ([]string, error) {
+	matches, err := filepath.Glob(w.Filename + ".*")
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := w.Filename + "."
+	backups := matches[:0]
+	for _, m := range matches {
+		if strings.HasSuffix(m, ".tmp") {
+			continue
+		}
+		suffix := strings.TrimSuffix(m[len(prefix):], ".gz")
+		if len(suffix) < len(BackupTimeLayout) {
+			continue
+		}
+		if _, err := time.Parse(BackupTimeLayout, suffix[:len(BackupTimeLayout)]); err == nil {
+			backups = append(backups, m)
+		}
+	}
+	sort.Strings(backups)
+
+	return backups, nil
+}
+
+func (w *RotateWriter) cleanup() {
+	if w.MaxBackups <= 0 {
+		return
+	}
+
+	backups, err := w.Backups()
+	if err != nil {
+		return
+	}
+	for i := 0; i < len(backups)-w.MaxBackups; i++ {
+		os.Remove(backups[i])
+	}
+}
+
+// Flush sync file to disk
+func (w *RotateWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.fd == nil {
+		return nil
+	}
+	return w.fd.Sync()
+}
+
+// Close the file and wait background compressions finish
+func (w *RotateWriter) Close() error {
+	w.mu.Lock()
+	var err error
+	if w.fd != nil {
+		err = w.fd.Close()
+		w.fd = nil
+	}
+	w.mu.Unlock()
+
+	w.wg.Wait()
+	return err
+}
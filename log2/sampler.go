@@ -0,0 +1,53 @@
+package log2
+
+import (
+	"sync"
+	"time"
+)
+
+// Sampler limit logs with same level and message, in each Tick, the First
+// entries are logged, after that only every Thereafter-th entry is logged.
+// Zero Thereafter drop all entries after the First ones.
+type Sampler struct {
+	Tick       time.Duration
+	First      int
+	Thereafter int
+
+	mu     sync.Mutex
+	start  time.Time
+	counts map[sampleKey]int
+}
+
+type sampleKey struct {
+	level Level
+	msg   string
+}
+
+func NewSampler(tick time.Duration, first, thereafter int) *Sampler {
+	return &Sampler{
+		Tick:       tick,
+		First:      first,
+		Thereafter: thereafter,
+	}
+}
+
+// Allow report whether the entry should be logged
+func (s *Sampler) Allow(level Level, msg string) bool {
+	now := time.Now()
+	key := sampleKey{level: level, msg: msg}
+
+	s.mu.Lock()
+	if s.counts == nil || now.Sub(s.start) >= s.Tick {
+		s.start = now
+		s.counts = make(map[sampleKey]int)
+	}
+	n := s.counts[key] + 1
+	s.counts[key] = n
+	s.mu.Unlock()
+
+	if n <= s.First {
+		return true
+	}
+
+	return s.Thereafter > 0 && (n-s.First)%s.Thereafter == 0
+}
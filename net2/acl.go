@@ -0,0 +1,141 @@
+package net2
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// ACL is a allow/deny list of networks, the longest matched network decide
+// whether a ip is allowed, ips match nothing use the default rule
+type ACL struct {
+	mu           sync.RWMutex
+	trie         *CIDRTrie
+	defaultAllow bool
+}
+
+// NewACL create a ACL, defaultAllow is used for ips match no network
+func NewACL(defaultAllow bool) *ACL {
+	return &ACL{
+		trie:         NewCIDRTrie(),
+		defaultAllow: defaultAllow,
+	}
+}
+
+// Allow add networks to allow list, ips are treated as full length prefix
+func (a *ACL) Allow(cidrs ...string) error {
+	return a.add(true, cidrs)
+}
+
+// Deny add networks to deny list, ips are treated as full length prefix
+func (a *ACL) Deny(cidrs ...string) error {
+	return a.add(false, cidrs)
+}
+
+func (a *ACL) add(allow bool, cidrs []string) error {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		network, err := ParseCIDR(cidr)
+		if err != nil {
+			return err
+		}
+		networks = append(networks, network)
+	}
+
+	a.mu.Lock()
+	for _, network := range networks {
+		a.trie.Insert(network, allow)
+	}
+	a.mu.Unlock()
+	return nil
+}
+
+// Remove remove a network from the list
+func (a *ACL) Remove(cidr string) error {
+	network, err := ParseCIDR(cidr)
+	if err == nil {
+		a.mu.Lock()
+		a.trie.Remove(network)
+		a.mu.Unlock()
+	}
+	return err
+}
+
+// Allowed check whether the ip is allowed
+func (a *ACL) Allowed(ip net.IP) bool {
+	a.mu.RLock()
+	_, allow, ok := a.trie.Lookup(ip)
+	a.mu.RUnlock()
+	if !ok {
+		return a.defaultAllow
+	}
+	return allow.(bool)
+}
+
+// AllowedAddr check whether ip of the address is allowed, non-IP addresses
+// such as unix sockets are always allowed
+func (a *ACL) AllowedAddr(addr net.Addr) bool {
+	if _, is := addr.(*net.UnixAddr); is {
+		return true
+	}
+	ip := addrIP(addr)
+	if ip == nil {
+		if _, _, err := net.SplitHostPort(addr.String()); err != nil {
+			return true
+		}
+		return a.defaultAllow
+	}
+	return a.Allowed(ip)
+}
+
+// addrIP return ip of the address, it's nil if the address has no ip
+func addrIP(addr net.Addr) net.IP {
+	switch addr := addr.(type) {
+	case *net.TCPAddr:
+		return addr.IP
+	case *net.UDPAddr:
+		return addr.IP
+	case *net.IPAddr:
+		return addr.IP
+	case *net.UnixAddr:
+		return nil
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return nil
+	}
+	return net.ParseIP(host)
+}
+
+// ACLListener close connections not allowed by ACL
+type ACLListener struct {
+	net.Listener
+	acl    *ACL
+	denied int64
+}
+
+func NewACLListener(l net.Listener, acl *ACL) *ACLListener {
+	return &ACLListener{
+		Listener: l,
+		acl:      acl,
+	}
+}
+
+func (l *ACLListener) Accept() (net.Conn, error) {
+	for {
+		c, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if l.acl.AllowedAddr(c.RemoteAddr()) {
+			return c, nil
+		}
+		atomic.AddInt64(&l.denied, 1)
+		c.Close()
+	}
+}
+
+// Denied return count of denied connections
+func (l *ACLListener) Denied() int64 {
+	return atomic.LoadInt64(&l.denied)
+}
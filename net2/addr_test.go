@@ -1,7 +1,95 @@
 package net2
 
-import "testing"
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/cosiner/gohper/testing2"
+)
 
 func TestAddr(t *testing.T) {
 	t.Log(ReplaceHost(":1080", Localhost()))
 }
+
+func TestCIDRTrie(t *testing.T) {
+	tt := testing2.Wrap(t)
+	trie := NewCIDRTrie()
+	for i, cidr := range []string{"10.0.0.0/8", "10.1.0.0/16", "10.1.2.0/24", "10.128.0.0/9", "0.0.0.0/0", "2001:db8::/32", "2001:db8:1::/48", "192.168.1.1"} {
+		tt.Nil(trie.InsertCIDR(cidr, i))
+	}
+	tt.NNil(trie.InsertCIDR("10.0.0.0/33", 0))
+	tt.Eq(8, trie.Len())
+
+	lookup := func(ip string) string {
+		network, value, ok := trie.Lookup(net.ParseIP(ip))
+		if !ok {
+			return ""
+		}
+		return fmt.Sprint(network, " ", value)
+	}
+	tt.Eq("10.1.2.0/24 2", lookup("10.1.2.3"))
+	tt.Eq("10.1.0.0/16 1", lookup("10.1.3.3"))
+	tt.Eq("10.0.0.0/8 0", lookup("10.2.3.4"))
+	tt.Eq("10.128.0.0/9 3", lookup("10.200.0.1"))
+	tt.Eq("0.0.0.0/0 4", lookup("11.0.0.1"))
+	tt.Eq("0.0.0.0/0 4", lookup("::ffff:11.0.0.1"))
+	tt.Eq("192.168.1.1/32 7", lookup("192.168.1.1"))
+	tt.Eq("0.0.0.0/0 4", lookup("192.168.1.2"))
+	tt.Eq("2001:db8:1::/48 6", lookup("2001:db8:1::1"))
+	tt.Eq("2001:db8::/32 5", lookup("2001:db8:2::1"))
+	tt.Eq("", lookup("2001:db9::1"))
+
+	network, _ := ParseCIDR("10.1.0.0/16")
+	value, ok := trie.Get(network)
+	tt.True(ok)
+	tt.Eq(1, value)
+	tt.True(trie.Remove(network))
+	tt.False(trie.Remove(network))
+	_, ok = trie.Get(network)
+	tt.False(ok)
+	tt.Eq("10.0.0.0/8 0", lookup("10.1.3.3"))
+	tt.Eq("10.1.2.0/24 2", lookup("10.1.2.3"))
+	network, _ = ParseCIDR("10.1.2.0/23")
+	tt.False(trie.Remove(network))
+	tt.Eq(7, trie.Len())
+
+	var walked []string
+	trie.Walk(func(network *net.IPNet, value interface{}) bool {
+		walked = append(walked, network.String())
+		return true
+	})
+	tt.DeepEq([]string{"0.0.0.0/0", "10.0.0.0/8", "10.1.2.0/24", "10.128.0.0/9", "192.168.1.1/32", "2001:db8::/32", "2001:db8:1::/48"}, walked)
+}
+
+func TestACL(t *testing.T) {
+	tt := testing2.Wrap(t)
+	acl := NewACL(false)
+	tt.Nil(acl.Allow("10.0.0.0/8", "::1"))
+	tt.Nil(acl.Deny("10.1.0.0/16"))
+	tt.NNil(acl.Deny("10.1.0.0/"))
+
+	tt.True(acl.Allowed(net.ParseIP("10.2.0.1")))
+	tt.False(acl.Allowed(net.ParseIP("10.1.0.1")))
+	tt.False(acl.Allowed(net.ParseIP("11.0.0.1")))
+	tt.True(acl.AllowedAddr(&net.TCPAddr{IP: net.ParseIP("::1"), Port: 80}))
+	tt.True(acl.AllowedAddr(&net.UnixAddr{Name: "/tmp/sock"}))
+	tt.Nil(acl.Remove("10.1.0.0/16"))
+	tt.True(acl.Allowed(net.ParseIP("10.1.0.1")))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	tt.Nil(err)
+	al := NewACLListener(l, acl)
+	defer al.Close()
+	c, err := net.Dial("tcp", l.Addr().String())
+	tt.Nil(err)
+	defer c.Close()
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		al.Close()
+	}()
+	_, err = al.Accept()
+	tt.NNil(err)
+	tt.Eq(int64(1), al.Denied())
+}
@@ -0,0 +1,282 @@
+package net2
+
+import (
+	"math/bits"
+	"net"
+)
+
+type cidrNode struct {
+	key      []byte // masked to bits
+	bits     int
+	children [2]*cidrNode
+	value    interface{}
+	has      bool
+}
+
+// CIDRTrie is a path-compressed binary trie of IPv4 and IPv6 prefixes with
+// values attached, lookups give the longest matched prefix in O(prefix length).
+// IPv4-mapped IPv6 addresses are treated as IPv4. CIDRTrie is not safe for
+// concurrent modification.
+type CIDRTrie struct {
+	v4, v6 *cidrNode
+	size   int
+}
+
+func NewCIDRTrie() *CIDRTrie {
+	return &CIDRTrie{}
+}
+
+// Len return count of prefixes
+func (t *CIDRTrie) Len() int {
+	return t.size
+}
+
+func (t *CIDRTrie) root(ip net.IP) (**cidrNode, []byte) {
+	if ip4 := ip.To4(); ip4 != nil {
+		return &t.v4, ip4
+	}
+	if ip16 := ip.To16(); ip16 != nil {
+		return &t.v6, ip16
+	}
+	return nil, nil
+}
+
+func (t *CIDRTrie) network(network *net.IPNet) (**cidrNode, []byte, int) {
+	root, key := t.root(network.IP)
+	if root == nil {
+		return nil, nil, 0
+	}
+	ones, size := network.Mask.Size()
+	if size == 8*net.IPv6len && len(key) == net.IPv4len {
+		// IPv4-mapped network such as ::ffff:10.0.0.0/104
+		if ones < 8*(net.IPv6len-net.IPv4len) {
+			return nil, nil, 0
+		}
+		ones -= 8 * (net.IPv6len - net.IPv4len)
+	} else if size != 8*len(key) {
+		return nil, nil, 0
+	}
+	return root, maskBits(key, ones), ones
+}
+
+// Insert add or replace value of the network, it return false if network
+// is invalid
+func (t *CIDRTrie) Insert(network *net.IPNet, value interface{}) bool {
+	n, key, ones := t.network(network)
+	if n == nil {
+		return false
+	}
+
+	for {
+		cur := *n
+		if cur == nil {
+			*n = &cidrNode{key: key, bits: ones, value: value, has: true}
+			t.size++
+			return true
+		}
+
+		common := commonBits(cur.key, key, minInt(cur.bits, ones))
+		switch {
+		case common == cur.bits && common == ones:
+			if !cur.has {
+				t.size++
+			}
+			cur.value, cur.has = value, true
+			return true
+		case common == cur.bits:
+			n = &cur.children[bitAt(key, cur.bits)]
+			continue
+		case common == ones:
+			node := &cidrNode{key: key, bits: ones, value: value, has: true}
+			node.children[bitAt(cur.key, ones)] = cur
+			*n = node
+		default:
+			branch := &cidrNode{key: maskBits(key, common), bits: common}
+			branch.children[bitAt(key, common)] = &cidrNode{key: key, bits: ones, value: value, has: true}
+			branch.children[bitAt(cur.key, common)] = cur
+			*n = branch
+		}
+		t.size++
+		return true
+	}
+}
+
+// InsertCIDR parse cidr like "10.0.0.0/8" and insert it, a single ip is
+// treated as a full length prefix
+func (t *CIDRTrie) InsertCIDR(cidr string, value interface{}) error {
+	network, err := ParseCIDR(cidr)
+	if err == nil {
+		t.Insert(network, value)
+	}
+	return err
+}
+
+// ParseCIDR parse cidr like "10.0.0.0/8", a single ip is treated as a full
+// length prefix
+func ParseCIDR(cidr string) (*net.IPNet, error) {
+	if ip := net.ParseIP(cidr); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(8*len(ip), 8*len(ip))}, nil
+	}
+	_, network, err := net.ParseCIDR(cidr)
+	return network, err
+}
+
+// Get return value of the network exactly
+func (t *CIDRTrie) Get(network *net.IPNet) (interface{}, bool) {
+	n, key, ones := t.network(network)
+	if n == nil {
+		return nil, false
+	}
+	for cur := *n; cur != nil && cur.bits <= ones; cur = cur.children[bitAt(key, cur.bits)] {
+		if commonBits(cur.key, key, cur.bits) < cur.bits {
+			break
+		}
+		if cur.bits == ones {
+			return cur.value, cur.has
+		}
+	}
+	return nil, false
+}
+
+// Remove remove the network, it return false if not exist
+func (t *CIDRTrie) Remove(network *net.IPNet) bool {
+	n, key, ones := t.network(network)
+	if n == nil {
+		return false
+	}
+
+	var parent **cidrNode
+	for *n != nil && (*n).bits <= ones && commonBits((*n).key, key, (*n).bits) == (*n).bits {
+		cur := *n
+		if cur.bits < ones {
+			parent, n = n, &cur.children[bitAt(key, cur.bits)]
+			continue
+		}
+		if !cur.has {
+			return false
+		}
+		cur.value, cur.has = nil, false
+		t.size--
+		compactNode(n)
+		if parent != nil {
+			compactNode(parent)
+		}
+		return true
+	}
+	return false
+}
+
+// compactNode remove the node without value if it has less than two children
+func compactNode(n **cidrNode) {
+	cur := *n
+	if cur.has {
+		return
+	}
+	switch {
+	case cur.children[0] == nil:
+		*n = cur.children[1]
+	case cur.children[1] == nil:
+		*n = cur.children[0]
+	}
+}
+
+// Lookup return the longest prefix contains the ip and its value
+func (t *CIDRTrie) Lookup(ip net.IP) (*net.IPNet, interface{}, bool) {
+	n, key := t.root(ip)
+	if n == nil {
+		return nil, nil, false
+	}
+
+	var best *cidrNode
+	for cur := *n; cur != nil; cur = cur.children[bitAt(key, cur.bits)] {
+		if commonBits(cur.key, key, cur.bits) < cur.bits {
+			break
+		}
+		if cur.has {
+			best = cur
+		}
+		if cur.bits == 8*len(key) {
+			break
+		}
+	}
+	if best == nil {
+		return nil, nil, false
+	}
+	return best.network(), best.value, true
+}
+
+// Contains check whether the ip is contained in any prefix
+func (t *CIDRTrie) Contains(ip net.IP) bool {
+	_, _, ok := t.Lookup(ip)
+	return ok
+}
+
+// Walk visit all prefixes, IPv4 first and in address order, it stop if fn
+// return false
+func (t *CIDRTrie) Walk(fn func(network *net.IPNet, value interface{}) bool) {
+	_ = walkCIDR(t.v4, fn) && walkCIDR(t.v6, fn)
+}
+
+func walkCIDR(n *cidrNode, fn func(*net.IPNet, interface{}) bool) bool {
+	if n == nil {
+		return true
+	}
+	if n.has && !fn(n.network(), n.value) {
+		return false
+	}
+	return walkCIDR(n.children[0], fn) && walkCIDR(n.children[1], fn)
+}
+
+func (n *cidrNode) network() *net.IPNet {
+	return &net.IPNet{
+		IP:   append(net.IP(nil), n.key...),
+		Mask: net.CIDRMask(n.bits, 8*len(n.key)),
+	}
+}
+
+func maskBits(key []byte, ones int) []byte {
+	masked := make([]byte, len(key))
+	for i := range masked {
+		switch {
+		case ones >= 8:
+			masked[i] = key[i]
+			ones -= 8
+		case ones > 0:
+			masked[i] = key[i] & ^byte(0xff>>uint(ones))
+			ones = 0
+		}
+	}
+	return masked
+}
+
+func commonBits(a, b []byte, max int) int {
+	var n int
+	for i := 0; n < max && i < len(a); i++ {
+		if x := a[i] ^ b[i]; x != 0 {
+			n += bits.LeadingZeros8(x)
+			break
+		}
+		n += 8
+	}
+	if n > max {
+		n = max
+	}
+	return n
+}
+
+func bitAt(key []byte, i int) int {
+	if i >= 8*len(key) {
+		return 0
+	}
+	return int(key[i/8]>>uint(7-i%8)) & 1
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
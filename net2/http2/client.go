@@ -0,0 +1,50 @@
+package http2
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/cosiner/gohper/net2"
+)
+
+// ClientIP return ip of the client, if remote address is a trusted proxy,
+// X-Forwarded-For is searched from right to left, the first untrusted ip is
+// the client. If trusted is nil, X-Forwarded-For is ignored.
+func ClientIP(r *http.Request, trusted *net2.CIDRTrie) net.IP {
+	ip := net.ParseIP(IpOfAddr(r.RemoteAddr))
+	if ip == nil || trusted == nil || !trusted.Contains(ip) {
+		return ip
+	}
+
+	values := r.Header["X-Forwarded-For"]
+	for i := len(values) - 1; i >= 0; i-- {
+		hops := strings.Split(values[i], ",")
+		for j := len(hops) - 1; j >= 0; j-- {
+			hop := net.ParseIP(IpOfAddr(strings.TrimSpace(hops[j])))
+			if hop == nil {
+				// malformed entry, don't trust anything before it
+				return ip
+			}
+			ip = hop
+			if !trusted.Contains(ip) {
+				return ip
+			}
+		}
+	}
+	return ip
+}
+
+// ACLHandler reject requests from clients not allowed by the ACL with status
+// 403, client ip is decided by ClientIP
+func ACLHandler(acl *net2.ACL, trusted *net2.CIDRTrie, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := ClientIP(r, trusted)
+		if ip == nil || !acl.Allowed(ip) {
+			code := http.StatusForbidden
+			http.Error(w, http.StatusText(code), code)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
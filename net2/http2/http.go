@@ -1,8 +1,18 @@
 package http2
 
-import "strings"
+import (
+	"net"
+	"strings"
+)
 
+// IpOfAddr return ip of address like "1.2.3.4:80", "[::1]:80" or a bare ip
 func IpOfAddr(addr string) string {
+	if net.ParseIP(addr) != nil {
+		return addr
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
 	i := strings.IndexByte(addr, ':')
 	if i >= 0 {
 		addr = addr[:i]
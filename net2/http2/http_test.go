@@ -0,0 +1,49 @@
+package http2
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cosiner/gohper/net2"
+	"github.com/cosiner/gohper/testing2"
+)
+
+func TestIpOfAddr(t *testing.T) {
+	testing2.
+		Expect("1.2.3.4").Arg("1.2.3.4:80").
+		Expect("1.2.3.4").Arg("1.2.3.4").
+		Expect("::1").Arg("[::1]:80").
+		Expect("2001:db8::1").Arg("2001:db8::1").
+		Run(t, IpOfAddr)
+}
+
+func TestClientIP(t *testing.T) {
+	tt := testing2.Wrap(t)
+	trusted := net2.NewCIDRTrie()
+	tt.Nil(trusted.InsertCIDR("10.0.0.0/8", nil))
+
+	req := func(remote string, xff ...string) *http.Request {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = remote
+		for _, v := range xff {
+			r.Header.Add("X-Forwarded-For", v)
+		}
+		return r
+	}
+	tt.Eq("1.1.1.1", ClientIP(req("1.1.1.1:80", "2.2.2.2"), trusted).String())
+	tt.Eq("2.2.2.2", ClientIP(req("10.0.0.1:80", "3.3.3.3, 2.2.2.2, 10.0.0.2"), trusted).String())
+	tt.Eq("2001:db8::1", ClientIP(req("10.0.0.1:80", "3.3.3.3", "2001:db8::1"), trusted).String())
+	tt.Eq("10.0.0.3", ClientIP(req("10.0.0.1:80", "10.0.0.3"), trusted).String())
+	tt.Eq("10.0.0.2", ClientIP(req("10.0.0.1:80", "1.1.1.1, bad, 10.0.0.2"), trusted).String())
+	tt.Eq("10.0.0.1", ClientIP(req("10.0.0.1:80", "2.2.2.2"), nil).String())
+
+	acl := net2.NewACL(true)
+	tt.Nil(acl.Deny("2.2.2.2"))
+	h := ACLHandler(acl, trusted, http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	for ip, code := range map[string]int{"2.2.2.2": http.StatusForbidden, "3.3.3.3": http.StatusOK} {
+		resp := httptest.NewRecorder()
+		h.ServeHTTP(resp, req("10.0.0.1:80", ip))
+		tt.Eq(code, resp.Code)
+	}
+}
@@ -3,8 +3,10 @@ package net2
 import (
 	"net"
 	"sync/atomic"
+	"time"
 
 	"github.com/cosiner/gohper/time2"
+	"github.com/cosiner/gohper/utils/retry"
 )
 
 type Sleeper struct {
@@ -38,13 +40,32 @@ func (s *Sleeper) Reset() {
 }
 
 type retryListener struct {
-	sleeper Sleeper
+	state *retry.State
 	net.Listener
 }
 
+// NewRetryListener retry accepting on temporary errors, the delay start from
+// minSleepMs and double until maxSleepMs
 func NewRetryListener(l net.Listener, minSleepMs, maxSleepMs int) net.Listener {
+	s := NewSleeper(minSleepMs, maxSleepMs)
+	return NewRetryListenerPolicy(l, &retry.Policy{
+		Backoff: retry.Exponential{
+			Initial: time.Duration(s.minSleepMs) * time.Millisecond,
+			Max:     time.Duration(s.maxSleepMs) * time.Millisecond,
+		},
+	})
+}
+
+// NewRetryListenerPolicy retry accepting by policy, if policy.Retryable is
+// nil, only temporary errors are retried
+func NewRetryListenerPolicy(l net.Listener, policy *retry.Policy) net.Listener {
+	if policy.Retryable == nil {
+		p := *policy
+		p.Retryable = retry.Temporary
+		policy = &p
+	}
 	return &retryListener{
-		sleeper:  NewSleeper(minSleepMs, maxSleepMs),
+		state:    policy.Start(),
 		Listener: l,
 	}
 }
@@ -61,12 +82,12 @@ func (r *retryListener) Accept() (net.Conn, error) {
 	for {
 		c, e := r.Listener.Accept()
 		if e != nil {
-			if ne, ok := e.(net.Error); ok && ne.Temporary() {
-				r.sleeper.Sleep()
+			if delay, ok := r.state.Next(e); ok {
+				r.state.Sleep(delay)
 				continue
 			}
 		}
-		r.sleeper.Reset()
+		r.state.Reset()
 		return c, e
 	}
 }
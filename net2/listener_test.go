@@ -0,0 +1,281 @@
+package net2
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cosiner/gohper/testing2"
+	"github.com/cosiner/gohper/utils/retry"
+)
+
+type tempError struct{}
+
+func (tempError) Error() string   { return "temporary" }
+func (tempError) Timeout() bool   { return false }
+func (tempError) Temporary() bool { return true }
+
+type errListener struct {
+	net.Listener
+	errs []error
+}
+
+func (l *errListener) Accept() (net.Conn, error) {
+	if len(l.errs) > 0 {
+		err := l.errs[0]
+		l.errs = l.errs[1:]
+		return nil, err
+	}
+	return nil, nil
+}
+
+func TestRetryListener(t *testing.T) {
+	tt := testing2.Wrap(t)
+	errFatal := errors.New("fatal")
+
+	var attempts int
+	l := NewRetryListenerPolicy(&errListener{errs: []error{tempError{}, tempError{}, errFatal, tempError{}}}, &retry.Policy{
+		Backoff:   retry.Constant(0),
+		OnAttempt: func(retry.Attempt) { attempts++ },
+	})
+	_, err := l.Accept()
+	tt.Eq(errFatal, err)
+	tt.Eq(3, attempts)
+	_, err = l.Accept()
+	tt.Nil(err)
+
+	l = NewRetryListener(&errListener{errs: []error{tempError{}}}, 1, 2)
+	_, err = l.Accept()
+	tt.Nil(err)
+}
+
+func TestTrackListener(t *testing.T) {
+	tt := testing2.Wrap(t)
+	trusted := NewCIDRTrie()
+	tt.Nil(trusted.InsertCIDR("127.0.0.1", nil))
+	l, err := TrackListen("tcp", "127.0.0.1:0", TrackConfig{
+		MaxConns:       2,
+		IdleTimeout:    500 * time.Millisecond,
+		ProxyProtocol:  true,
+		TrustedProxies: trusted,
+	})
+	tt.Nil(err)
+	addr := l.Addr().String()
+
+	accepted := make(chan net.Conn, 4)
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				close(accepted)
+				return
+			}
+			accepted <- c
+		}
+	}()
+
+	c1, err := net.Dial("tcp", addr)
+	tt.Nil(err)
+	_, err = c1.Write([]byte("PROXY TCP4 1.2.3.4 5.6.7.8 1000 80\r\nhello"))
+	tt.Nil(err)
+	s1 := <-accepted
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(s1, buf)
+	tt.Nil(err)
+	tt.Eq("hello", string(buf))
+	tt.Eq("1.2.3.4:1000", s1.RemoteAddr().String())
+	tt.Eq("5.6.7.8:80", s1.LocalAddr().String())
+
+	c2, err := net.Dial("tcp", addr)
+	tt.Nil(err)
+	_, err = c2.Write([]byte("x"))
+	tt.Nil(err)
+	s2 := <-accepted
+	tt.Eq(c2.LocalAddr().String(), s2.RemoteAddr().String())
+
+	// exceed MaxConns
+	c3, err := net.Dial("tcp", addr)
+	tt.Nil(err)
+	_, err = c3.Read(buf)
+	tt.NNil(err)
+	tt.Eq(2, len(l.Conns()))
+	tt.Eq(TrackStats{Accepted: 3, Active: 2, Rejected: 1}, l.Stats())
+
+	// idle connections are closed
+	s1.Close()
+	testing2.Eventually(t, func() bool { return l.Stats().Active == 0 }, 2*time.Second, 10*time.Millisecond)
+	_, err = c2.Read(buf)
+	tt.NNil(err)
+
+	c4, err := net.Dial("tcp", addr)
+	tt.Nil(err)
+	s4 := <-accepted
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		s4.Close()
+	}()
+	tt.Nil(l.Shutdown(context.Background()))
+	_, is := <-accepted
+	tt.False(is)
+	c1.Close()
+	c2.Close()
+	c3.Close()
+	c4.Close()
+}
+
+func TestTrackListenerProxyTrust(t *testing.T) {
+	tt := testing2.Wrap(t)
+	dial := func(config TrackConfig, data string) (net.Conn, net.Conn) {
+		l, err := TrackListen("tcp", "127.0.0.1:0", config)
+		tt.Nil(err)
+		defer l.Close()
+		c, err := net.Dial("tcp", l.Addr().String())
+		tt.Nil(err)
+		_, err = c.Write([]byte(data))
+		tt.Nil(err)
+		s, err := l.Accept()
+		tt.Nil(err)
+		return c, s
+	}
+	header := "PROXY TCP4 1.2.3.4 5.6.7.8 1000 80\r\n"
+
+	// header from untrusted peer is data
+	untrusted := NewCIDRTrie()
+	tt.Nil(untrusted.InsertCIDR("10.0.0.0/8", nil))
+	c, s := dial(TrackConfig{ProxyProtocol: true, TrustedProxies: untrusted}, header)
+	tt.Eq(c.LocalAddr().String(), s.RemoteAddr().String())
+	buf := make([]byte, len(header))
+	_, err := io.ReadFull(s, buf)
+	tt.Nil(err)
+	tt.Eq(header, string(buf))
+	c.Close()
+	s.Close()
+
+	// header is required without trusted proxies
+	l, err := TrackListen("tcp", "127.0.0.1:0", TrackConfig{ProxyProtocol: true, ProxyHeaderTimeout: 50 * time.Millisecond})
+	tt.Nil(err)
+	defer l.Close()
+	for _, data := range []string{"x", "", header} {
+		c, err := net.Dial("tcp", l.Addr().String())
+		tt.Nil(err)
+		defer c.Close()
+		if data != "" {
+			_, err = c.Write([]byte(data))
+			tt.Nil(err)
+		}
+	}
+	s, err = l.Accept()
+	tt.Nil(err)
+	tt.Eq("1.2.3.4:1000", s.RemoteAddr().String())
+	s.Close()
+	testing2.Eventually(t, func() bool { return l.Stats().Rejected == 2 }, time.Second, 10*time.Millisecond)
+}
+
+func TestTrackListenerSlowProxy(t *testing.T) {
+	tt := testing2.Wrap(t)
+	trusted := NewCIDRTrie()
+	tt.Nil(trusted.InsertCIDR("127.0.0.0/8", nil))
+	l, err := TrackListen("tcp", "127.0.0.1:0", TrackConfig{
+		ProxyProtocol:      true,
+		TrustedProxies:     trusted,
+		ProxyHeaderTimeout: 300 * time.Millisecond,
+	})
+	tt.Nil(err)
+
+	slow, err := net.Dial("tcp", l.Addr().String())
+	tt.Nil(err)
+	defer slow.Close()
+	c, err := net.Dial("tcp", l.Addr().String())
+	tt.Nil(err)
+	defer c.Close()
+	_, err = c.Write([]byte("PROXY TCP4 1.2.3.4 5.6.7.8 1000 80\r\n"))
+	tt.Nil(err)
+
+	start := time.Now()
+	s, err := l.Accept()
+	tt.Nil(err)
+	tt.True(time.Since(start) < 200*time.Millisecond)
+	tt.Eq("1.2.3.4:1000", s.RemoteAddr().String())
+
+	// no data from trusted peer in time, it's direct
+	s, err = l.Accept()
+	tt.Nil(err)
+	tt.Eq(slow.LocalAddr().String(), s.RemoteAddr().String())
+	tt.Eq(int64(0), l.Stats().Rejected)
+
+	tt.Nil(l.Close())
+	_, err = l.Accept()
+	tt.NNil(err)
+	_, err = l.Accept()
+	tt.NNil(err)
+}
+
+func TestTrackListenerShutdownTimeout(t *testing.T) {
+	tt := testing2.Wrap(t)
+	l, err := TrackListen("tcp", "127.0.0.1:0", TrackConfig{})
+	tt.Nil(err)
+	c, err := net.Dial("tcp", l.Addr().String())
+	tt.Nil(err)
+	defer c.Close()
+	s, err := l.Accept()
+	tt.Nil(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	tt.Eq(context.DeadlineExceeded, l.Shutdown(ctx))
+	_, err = s.Read(make([]byte, 1))
+	tt.NNil(err)
+	tt.Eq(int64(0), l.Stats().Active)
+}
+
+func TestReadProxyHeader(t *testing.T) {
+	tt := testing2.Wrap(t)
+	read := func(s string) (*ProxyHeader, error) {
+		return ReadProxyHeader(bufio.NewReader(strings.NewReader(s)))
+	}
+
+	h, err := read("PROXY TCP6 ::1 ::2 1 2\r\n")
+	tt.Nil(err)
+	tt.Eq("[::1]:1", h.Source.String())
+	h, err = read("PROXY UNKNOWN\r\n")
+	tt.Nil(err)
+	tt.True(h.Local)
+	for _, s := range []string{
+		"PROXY TCP4 1.2.3.4 ::1 1 2\r\n",
+		"PROXY TCP4 1.2.3.4 1.2.3.4 1 70000\r\n",
+		"PROXY TCP4 1.2.3.4 1.2.3.4 1 2\n",
+		"PROXY " + strings.Repeat("x", 200),
+	} {
+		_, err = read(s)
+		tt.Eq(ErrInvalidProxyHeader, err)
+	}
+	_, err = read("GET / HTTP/1.1\r\n")
+	tt.Eq(ErrNoProxyHeader, err)
+	_, err = read("")
+	tt.Eq(ErrNoProxyHeader, err)
+
+	v2 := func(cmd, family byte, addr []byte) string {
+		head := append([]byte(nil), proxyV2Signature...)
+		head = append(head, 0x20|cmd, family, byte(len(addr)>>8), byte(len(addr)))
+		return string(append(head, addr...)) + "rest"
+	}
+	r := bufio.NewReader(strings.NewReader(v2(1, 0x11, []byte{1, 2, 3, 4, 5, 6, 7, 8, 0, 80, 1, 0, 9, 9})))
+	h, err = ReadProxyHeader(r)
+	tt.Nil(err)
+	tt.Eq(2, h.Version)
+	tt.Eq("1.2.3.4:80", h.Source.String())
+	tt.Eq("5.6.7.8:256", h.Destination.String())
+	rest, _ := r.ReadString(0)
+	tt.Eq("rest", rest)
+
+	h, err = read(v2(0, 0, nil))
+	tt.Nil(err)
+	tt.True(h.Local)
+	_, err = read(v2(1, 0x21, []byte{1, 2, 3}))
+	tt.Eq(ErrInvalidProxyHeader, err)
+}
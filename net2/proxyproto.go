@@ -0,0 +1,168 @@
+package net2
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/cosiner/gohper/errors"
+)
+
+const (
+	ErrInvalidProxyHeader = errors.Err("net2: invalid proxy protocol header")
+	ErrNoProxyHeader      = errors.Err("net2: no proxy protocol header")
+)
+
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+const proxyV1MaxLen = 107
+
+// ProxyHeader is the header of PROXY protocol v1 and v2
+type ProxyHeader struct {
+	Version int
+	// Local is true for v2 LOCAL command and v1 UNKNOWN protocol, the
+	// connection is not proxied and addresses are nil
+	Local       bool
+	Source      net.Addr
+	Destination net.Addr
+}
+
+// ReadProxyHeader read PROXY protocol header, ErrNoProxyHeader is returned
+// and nothing is consumed if data doesn't start with a header or there is no
+// data, such as timeout or EOF
+func ReadProxyHeader(r *bufio.Reader) (*ProxyHeader, error) {
+	b, err := r.Peek(1)
+	if err != nil {
+		return nil, ErrNoProxyHeader
+	}
+	switch b[0] {
+	case 'P':
+		if b, err = r.Peek(6); err != nil || string(b) != "PROXY " {
+			return nil, ErrNoProxyHeader
+		}
+		return readProxyV1(r)
+	case '\r':
+		if b, err = r.Peek(len(proxyV2Signature)); err != nil || !bytes.Equal(b, proxyV2Signature) {
+			return nil, ErrNoProxyHeader
+		}
+		return readProxyV2(r)
+	}
+	return nil, ErrNoProxyHeader
+}
+
+func readProxyV1(r *bufio.Reader) (*ProxyHeader, error) {
+	var line []byte
+	for len(line) < proxyV1MaxLen {
+		c, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, c)
+		if c == '\n' {
+			break
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, ErrInvalidProxyHeader
+	}
+
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+	h := &ProxyHeader{Version: 1}
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		h.Local = true
+		return h, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, ErrInvalidProxyHeader
+	}
+	src, dst := net.ParseIP(fields[2]), net.ParseIP(fields[3])
+	srcPort, err1 := strconv.ParseUint(fields[4], 10, 16)
+	dstPort, err2 := strconv.ParseUint(fields[5], 10, 16)
+	if src == nil || dst == nil || err1 != nil || err2 != nil ||
+		(fields[1] == "TCP4") != (src.To4() != nil && dst.To4() != nil) {
+		return nil, ErrInvalidProxyHeader
+	}
+	h.Source = &net.TCPAddr{IP: src, Port: int(srcPort)}
+	h.Destination = &net.TCPAddr{IP: dst, Port: int(dstPort)}
+	return h, nil
+}
+
+func readProxyV2(r *bufio.Reader) (*ProxyHeader, error) {
+	head := make([]byte, 16)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return nil, err
+	}
+	verCmd, family := head[12], head[13]
+	if verCmd>>4 != 2 {
+		return nil, ErrInvalidProxyHeader
+	}
+	data := make([]byte, binary.BigEndian.Uint16(head[14:]))
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+
+	h := &ProxyHeader{Version: 2}
+	switch verCmd & 0xf {
+	case 0:
+		h.Local = true
+		return h, nil
+	case 1:
+	default:
+		return nil, ErrInvalidProxyHeader
+	}
+
+	var ipLen int
+	switch family >> 4 {
+	case 0: // UNSPEC
+		h.Local = true
+		return h, nil
+	case 1:
+		ipLen = net.IPv4len
+	case 2:
+		ipLen = net.IPv6len
+	case 3:
+		const pathLen = 108
+		if len(data) < 2*pathLen {
+			return nil, ErrInvalidProxyHeader
+		}
+		netname := "unix"
+		if family&0xf == 2 {
+			netname = "unixgram"
+		}
+		h.Source = &net.UnixAddr{Name: cString(data[:pathLen]), Net: netname}
+		h.Destination = &net.UnixAddr{Name: cString(data[pathLen : 2*pathLen]), Net: netname}
+		return h, nil
+	default:
+		return nil, ErrInvalidProxyHeader
+	}
+	if len(data) < 2*ipLen+4 {
+		return nil, ErrInvalidProxyHeader
+	}
+
+	src := net.IP(append([]byte(nil), data[:ipLen]...))
+	dst := net.IP(append([]byte(nil), data[ipLen:2*ipLen]...))
+	srcPort := int(binary.BigEndian.Uint16(data[2*ipLen:]))
+	dstPort := int(binary.BigEndian.Uint16(data[2*ipLen+2:]))
+	switch family & 0xf {
+	case 1:
+		h.Source = &net.TCPAddr{IP: src, Port: srcPort}
+		h.Destination = &net.TCPAddr{IP: dst, Port: dstPort}
+	case 2:
+		h.Source = &net.UDPAddr{IP: src, Port: srcPort}
+		h.Destination = &net.UDPAddr{IP: dst, Port: dstPort}
+	default:
+		return nil, ErrInvalidProxyHeader
+	}
+	return h, nil
+}
+
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
@@ -0,0 +1,414 @@
+package net2
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cosiner/gohper/utils/retry"
+)
+
+// TrackConfig is options of TrackListener
+type TrackConfig struct {
+	// ReadTimeout and WriteTimeout are set as deadline before each Read and
+	// Write if not 0
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	// IdleTimeout close connections without reading or writing for the
+	// duration, it's disabled if 0
+	IdleTimeout time.Duration
+	// MaxConns reject connections exceed it, unlimited if 0
+	MaxConns int64
+	// ProxyProtocol enable parsing PROXY protocol v1/v2 header
+	ProxyProtocol bool
+	// TrustedProxies is the peers allowed to send PROXY protocol header,
+	// connections from others are treated as direct and the header is not
+	// parsed, connections from trusted peers without header are also direct.
+	// If it's nil, the header is required from every peer and connections
+	// without it are rejected.
+	TrustedProxies *CIDRTrie
+	// ProxyHeaderTimeout default 5s
+	ProxyHeaderTimeout time.Duration
+}
+
+// TrackStats is metrics of TrackListener
+type TrackStats struct {
+	Accepted int64
+	Active   int64
+	Rejected int64
+}
+
+// TrackListener track accepted connections, it support deadlines, idle
+// timeout, connection limit and graceful shutdown
+type TrackListener struct {
+	net.Listener
+	config TrackConfig
+
+	accepted int64
+	rejected int64
+	nextID   int64
+
+	mu      sync.Mutex
+	conns   map[*TrackedConn]struct{}
+	closed  bool
+	drained chan struct{}
+	stop    chan struct{}
+
+	// background accepting for PROXY protocol
+	acceptOnce sync.Once
+	ready      chan acceptResult
+	acceptDone chan struct{}
+	acceptErr  error
+}
+
+func NewTrackListener(l net.Listener, config TrackConfig) *TrackListener {
+	if config.ProxyHeaderTimeout <= 0 {
+		config.ProxyHeaderTimeout = 5 * time.Second
+	}
+	t := &TrackListener{
+		Listener: l,
+		config:   config,
+		conns:    make(map[*TrackedConn]struct{}),
+		stop:     make(chan struct{}),
+
+		ready:      make(chan acceptResult),
+		acceptDone: make(chan struct{}),
+	}
+	if config.IdleTimeout > 0 {
+		go t.reapIdle()
+	}
+	return t
+}
+
+func TrackListen(netname, addr string, config TrackConfig) (*TrackListener, error) {
+	l, err := net.Listen(netname, addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewTrackListener(l, config), nil
+}
+
+// Accept return next connection, if PROXY protocol is enabled, headers are
+// read in background and connections are returned after that, so a slow
+// client won't block others
+func (t *TrackListener) Accept() (net.Conn, error) {
+	if !t.config.ProxyProtocol {
+		c, err := t.accept()
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+
+	t.acceptOnce.Do(func() {
+		go t.acceptLoop()
+	})
+	select {
+	case r := <-t.ready:
+		if r.err != nil {
+			return nil, r.err
+		}
+		return r.conn, nil
+	case <-t.acceptDone:
+		return nil, t.acceptErr
+	}
+}
+
+func (t *TrackListener) accept() (*TrackedConn, error) {
+	for {
+		c, err := t.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		atomic.AddInt64(&t.accepted, 1)
+
+		tc := t.track(c)
+		if tc == nil {
+			atomic.AddInt64(&t.rejected, 1)
+			c.Close()
+			continue
+		}
+		return tc, nil
+	}
+}
+
+type acceptResult struct {
+	conn *TrackedConn
+	err  error
+}
+
+func (t *TrackListener) acceptLoop() {
+	for {
+		c, err := t.accept()
+		if err == nil {
+			go t.readProxy(c)
+			continue
+		}
+
+		if retry.Temporary(err) {
+			select {
+			case t.ready <- acceptResult{err: err}:
+				continue
+			case <-t.stop:
+			}
+		}
+		t.acceptErr = err
+		close(t.acceptDone)
+		return
+	}
+}
+
+func (t *TrackListener) readProxy(c *TrackedConn) {
+	if _, err := c.Proxy(); err != nil {
+		return // rejected and closed
+	}
+	select {
+	case t.ready <- acceptResult{conn: c}:
+	case <-t.stop:
+		c.Close()
+	}
+}
+
+func (t *TrackListener) track(c net.Conn) *TrackedConn {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed || (t.config.MaxConns > 0 && int64(len(t.conns)) >= t.config.MaxConns) {
+		return nil
+	}
+
+	now := time.Now()
+	tc := &TrackedConn{
+		Conn:     c,
+		l:        t,
+		id:       atomic.AddInt64(&t.nextID, 1),
+		created:  now,
+		lastUsed: now.UnixNano(),
+	}
+	if t.config.ProxyProtocol {
+		tc.reader = bufio.NewReader(c)
+	}
+	t.conns[tc] = struct{}{}
+	return tc
+}
+
+func (t *TrackListener) untrack(c *TrackedConn) {
+	t.mu.Lock()
+	delete(t.conns, c)
+	if len(t.conns) == 0 && t.drained != nil {
+		close(t.drained)
+		t.drained = nil
+	}
+	t.mu.Unlock()
+}
+
+// Stats return metrics of the listener
+func (t *TrackListener) Stats() TrackStats {
+	t.mu.Lock()
+	active := len(t.conns)
+	t.mu.Unlock()
+	return TrackStats{
+		Accepted: atomic.LoadInt64(&t.accepted),
+		Active:   int64(active),
+		Rejected: atomic.LoadInt64(&t.rejected),
+	}
+}
+
+// Conns return live connections
+func (t *TrackListener) Conns() []*TrackedConn {
+	t.mu.Lock()
+	conns := make([]*TrackedConn, 0, len(t.conns))
+	for c := range t.conns {
+		conns = append(conns, c)
+	}
+	t.mu.Unlock()
+	return conns
+}
+
+// Close stop accepting, live connections are not affected
+func (t *TrackListener) Close() error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	close(t.stop)
+	t.mu.Unlock()
+	return t.Listener.Close()
+}
+
+// Shutdown stop accepting and wait for live connections to be closed, if
+// context is done before that, remaining connections are closed forcibly and
+// context's error is returned
+func (t *TrackListener) Shutdown(ctx context.Context) error {
+	err := t.Close()
+
+	t.mu.Lock()
+	if len(t.conns) == 0 {
+		t.mu.Unlock()
+		return err
+	}
+	if t.drained == nil {
+		t.drained = make(chan struct{})
+	}
+	drained := t.drained
+	t.mu.Unlock()
+
+	select {
+	case <-drained:
+		return err
+	case <-ctx.Done():
+		for _, c := range t.Conns() {
+			c.Close()
+		}
+		return ctx.Err()
+	}
+}
+
+func (t *TrackListener) reapIdle() {
+	interval := t.config.IdleTimeout / 2
+	if interval < 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	stop := t.stop
+	for {
+		select {
+		case <-stop:
+			stop = nil
+		case <-ticker.C:
+		}
+		// keep reaping after closed until connections are drained
+		if stop == nil && t.Stats().Active == 0 {
+			return
+		}
+		for _, c := range t.Conns() {
+			if c.Idle() >= t.config.IdleTimeout {
+				c.Close()
+			}
+		}
+	}
+}
+
+// TrackedConn is connection accepted by TrackListener
+type TrackedConn struct {
+	net.Conn
+	l        *TrackListener
+	id       int64
+	created  time.Time
+	lastUsed int64
+
+	reader    *bufio.Reader
+	proxyOnce sync.Once
+	proxy     *ProxyHeader
+	proxyErr  error
+	closeOnce sync.Once
+}
+
+// ID return a unique id of the connection in its listener
+func (c *TrackedConn) ID() int64 {
+	return c.id
+}
+
+func (c *TrackedConn) Created() time.Time {
+	return c.created
+}
+
+// Idle return duration since last read or write
+func (c *TrackedConn) Idle() time.Duration {
+	return time.Since(time.Unix(0, atomic.LoadInt64(&c.lastUsed)))
+}
+
+func (c *TrackedConn) touch() {
+	atomic.StoreInt64(&c.lastUsed, time.Now().UnixNano())
+}
+
+// Proxy return PROXY protocol header of the connection, it's nil if not
+// enabled, the peer is not trusted or there is no header from trusted peer
+func (c *TrackedConn) Proxy() (*ProxyHeader, error) {
+	if c.reader == nil {
+		return nil, nil
+	}
+	c.proxyOnce.Do(func() {
+		trusted := c.l.config.TrustedProxies
+		if trusted != nil {
+			if ip := addrIP(c.Conn.RemoteAddr()); ip == nil || !trusted.Contains(ip) {
+				return
+			}
+		}
+
+		c.Conn.SetReadDeadline(time.Now().Add(c.l.config.ProxyHeaderTimeout))
+		c.proxy, c.proxyErr = ReadProxyHeader(c.reader)
+		c.Conn.SetReadDeadline(time.Time{})
+		if c.proxyErr == ErrNoProxyHeader && trusted != nil {
+			c.proxy, c.proxyErr = nil, nil
+		}
+		if c.proxyErr != nil {
+			atomic.AddInt64(&c.l.rejected, 1)
+			c.Close()
+		}
+	})
+	return c.proxy, c.proxyErr
+}
+
+// RemoteAddr return source address in PROXY protocol header if exist
+func (c *TrackedConn) RemoteAddr() net.Addr {
+	if h, _ := c.Proxy(); h != nil && h.Source != nil {
+		return h.Source
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// LocalAddr return destination address in PROXY protocol header if exist
+func (c *TrackedConn) LocalAddr() net.Addr {
+	if h, _ := c.Proxy(); h != nil && h.Destination != nil {
+		return h.Destination
+	}
+	return c.Conn.LocalAddr()
+}
+
+func (c *TrackedConn) Read(b []byte) (int, error) {
+	if _, err := c.Proxy(); err != nil {
+		return 0, err
+	}
+	if d := c.l.config.ReadTimeout; d > 0 {
+		c.Conn.SetReadDeadline(time.Now().Add(d))
+	}
+
+	var (
+		n   int
+		err error
+	)
+	if c.reader != nil {
+		n, err = c.reader.Read(b)
+	} else {
+		n, err = c.Conn.Read(b)
+	}
+	if n > 0 {
+		c.touch()
+	}
+	return n, err
+}
+
+func (c *TrackedConn) Write(b []byte) (int, error) {
+	if d := c.l.config.WriteTimeout; d > 0 {
+		c.Conn.SetWriteDeadline(time.Now().Add(d))
+	}
+	n, err := c.Conn.Write(b)
+	if n > 0 {
+		c.touch()
+	}
+	return n, err
+}
+
+func (c *TrackedConn) Close() error {
+	err := c.Conn.Close()
+	c.closeOnce.Do(func() {
+		c.l.untrack(c)
+	})
+	return err
+}
@@ -0,0 +1,175 @@
+package url2
+
+import (
+	"net/url"
+	"strings"
+)
+
+// QueryBuilder build query string with parameters in insertion order, a key
+// may have multiple values, keys and values are escaped separately
+type QueryBuilder struct {
+	keys   []string
+	values []string
+}
+
+func NewQuery() *QueryBuilder {
+	return &QueryBuilder{}
+}
+
+// ParseQuery parse query string, order of parameters is kept
+func ParseQuery(query string) (*QueryBuilder, error) {
+	q := NewQuery()
+	for query != "" {
+		var part string
+		if i := strings.IndexByte(query, '&'); i >= 0 {
+			part, query = query[:i], query[i+1:]
+		} else {
+			part, query = query, ""
+		}
+		if part == "" {
+			continue
+		}
+
+		key, value := part, ""
+		if i := strings.IndexByte(part, '='); i >= 0 {
+			key, value = part[:i], part[i+1:]
+		}
+		key, err := url.QueryUnescape(key)
+		if err != nil {
+			return nil, err
+		}
+		value, err = url.QueryUnescape(value)
+		if err != nil {
+			return nil, err
+		}
+		q.Add(key, value)
+	}
+	return q, nil
+}
+
+// Add append values of the key
+func (q *QueryBuilder) Add(key string, values ...string) *QueryBuilder {
+	for _, v := range values {
+		q.keys = append(q.keys, key)
+		q.values = append(q.values, v)
+	}
+	return q
+}
+
+// Set replace values of the key, the position of first existed value is kept
+func (q *QueryBuilder) Set(key string, values ...string) *QueryBuilder {
+	i := q.index(key)
+	if i < 0 {
+		return q.Add(key, values...)
+	}
+
+	keys := make([]string, 0, len(q.keys)+len(values))
+	vals := make([]string, 0, len(q.keys)+len(values))
+	keys, vals = append(keys, q.keys[:i]...), append(vals, q.values[:i]...)
+	for _, v := range values {
+		keys, vals = append(keys, key), append(vals, v)
+	}
+	for j := i; j < len(q.keys); j++ {
+		if q.keys[j] != key {
+			keys, vals = append(keys, q.keys[j]), append(vals, q.values[j])
+		}
+	}
+	q.keys, q.values = keys, vals
+	return q
+}
+
+// Del remove all values of the key
+func (q *QueryBuilder) Del(key string) *QueryBuilder {
+	var n int
+	for i, k := range q.keys {
+		if k != key {
+			q.keys[n], q.values[n] = k, q.values[i]
+			n++
+		}
+	}
+	q.keys, q.values = q.keys[:n], q.values[:n]
+	return q
+}
+
+func (q *QueryBuilder) index(key string) int {
+	for i, k := range q.keys {
+		if k == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// Has check whether the key exists
+func (q *QueryBuilder) Has(key string) bool {
+	return q.index(key) >= 0
+}
+
+// Get return first value of the key
+func (q *QueryBuilder) Get(key string) string {
+	if i := q.index(key); i >= 0 {
+		return q.values[i]
+	}
+	return ""
+}
+
+// Values return all values of the key
+func (q *QueryBuilder) Values(key string) []string {
+	var values []string
+	for i, k := range q.keys {
+		if k == key {
+			values = append(values, q.values[i])
+		}
+	}
+	return values
+}
+
+// Keys return distinct keys in order of first appearance
+func (q *QueryBuilder) Keys() []string {
+	keys := make([]string, 0, len(q.keys))
+	for i, k := range q.keys {
+		if q.index(k) == i {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// Len return count of parameters, values of a same key are counted separately
+func (q *QueryBuilder) Len() int {
+	return len(q.keys)
+}
+
+// URLValues convert to url.Values
+func (q *QueryBuilder) URLValues() url.Values {
+	values := make(url.Values, len(q.keys))
+	for i, k := range q.keys {
+		values[k] = append(values[k], q.values[i])
+	}
+	return values
+}
+
+// AppendTo append escaped query string to buf
+func (q *QueryBuilder) AppendTo(buf []byte) []byte {
+	for i, k := range q.keys {
+		if i != 0 {
+			buf = append(buf, '&')
+		}
+		buf = append(buf, url.QueryEscape(k)...)
+		buf = append(buf, '=')
+		buf = append(buf, url.QueryEscape(q.values[i])...)
+	}
+	return buf
+}
+
+// Encode return escaped query string
+func (q *QueryBuilder) Encode() string {
+	if len(q.keys) == 0 {
+		return ""
+	}
+	return string(q.AppendTo(make([]byte, 0, Bufsize)))
+}
+
+func (q *QueryBuilder) String() string {
+	return q.Encode()
+}
@@ -14,7 +14,8 @@ var emptyBytes = []byte("")
 
 // Query parameters to url query string without escape,
 // if buf is not nil, and there is more than one parameter, the allocated buffer
-// will stored to *buf
+// will stored to *buf.
+// Parameters are in random order, use QueryBuilder for deterministic order.
 func Query(params map[string]string, buf *bytes.Buffer) ([]byte, bool) {
 	l := len(params)
 	if l == 0 {
@@ -47,7 +48,8 @@ func Query(params map[string]string, buf *bytes.Buffer) ([]byte, bool) {
 	return nbuf.Bytes(), buf != nil
 }
 
-// QueryEscape is same as Query, but escape the query string
+// QueryEscape is same as Query, but escape the query string, '=' and '&' are
+// also escaped, use QueryBuilder to escape keys and values separately
 func QueryEscape(params map[string]string, buf *bytes.Buffer) ([]byte, bool) {
 	s, b := Query(params, buf)
 	if len(s) == 0 {
@@ -3,6 +3,7 @@ package url2
 import (
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/cosiner/gohper/testing2"
 )
@@ -30,3 +31,110 @@ func TestParamString(t *testing.T) {
 
 	Param(int64(2))
 }
+
+func TestQueryBuilder(t *testing.T) {
+	tt := testing2.Wrap(t)
+	q := NewQuery().
+		Add("b", "1 2").
+		Add("a", "x&y", "z=").
+		Add("c", "").
+		Add("b", "3")
+	tt.Eq("b=1+2&a=x%26y&a=z%3D&c=&b=3", q.Encode())
+	tt.DeepEq([]string{"b", "a", "c"}, q.Keys())
+	tt.DeepEq([]string{"1 2", "3"}, q.Values("b"))
+	tt.Eq("x&y", q.Get("a"))
+	tt.Eq(5, q.Len())
+
+	q.Set("a", "1")
+	tt.Eq("b=1+2&a=1&c=&b=3", q.Encode())
+	q.Set("d", "4").Del("b")
+	tt.Eq("a=1&c=&d=4", q.String())
+	tt.False(q.Has("b"))
+	tt.DeepEq(url.Values{"a": {"1"}, "c": {""}, "d": {"4"}}, q.URLValues())
+
+	p, err := ParseQuery("b=1+2&a=x%26y&&a=z%3D&c&b=3")
+	tt.Nil(err)
+	tt.Eq("b=1+2&a=x%26y&a=z%3D&c=&b=3", p.Encode())
+	_, err = ParseQuery("a=%zz")
+	tt.NNil(err)
+	tt.Eq("", NewQuery().Encode())
+}
+
+type pageQuery struct {
+	Page  int `query:"page,omitempty"`
+	Limit int `query:"limit"`
+}
+
+type searchQuery struct {
+	pageQuery
+	Keyword  string        `query:"q"`
+	Tags     []string      `query:"tag"`
+	IDs      []uint16      `query:"id,omitempty"`
+	Ratio    *float64      `query:"ratio"`
+	Exact    bool          `query:"exact,omitempty"`
+	Since    time.Time     `query:"since"`
+	Until    time.Time     `query:"until,unix,omitempty"`
+	Timeout  time.Duration `query:"timeout"`
+	Internal string        `query:"-"`
+	Sort     string
+	hidden   string
+}
+
+func TestQueryStruct(t *testing.T) {
+	tt := testing2.Wrap(t)
+	ratio := 0.5
+	s := searchQuery{
+		pageQuery: pageQuery{Limit: 10},
+		Keyword:   "a b",
+		Tags:      []string{"x", "y&z"},
+		Ratio:     &ratio,
+		Since:     time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC),
+		Until:     time.Unix(1451703845, 0),
+		Timeout:   1500 * time.Millisecond,
+		Internal:  "i",
+		Sort:      "name",
+		hidden:    "h",
+	}
+	query, err := MarshalQuery(&s, "query")
+	tt.Nil(err)
+	tt.Eq("limit=10&q=a+b&tag=x&tag=y%26z&ratio=0.5&since=2016-01-02T03%3A04%3A05Z&until=1451703845&timeout=1.5s&sort=name", query)
+
+	var d searchQuery
+	d.Internal = "keep"
+	tt.Nil(UnmarshalQuery(query+"&page=2&id=1&id=2&exact=true", &d, "query"))
+	tt.Eq(2, d.Page)
+	tt.Eq(10, d.Limit)
+	tt.Eq("a b", d.Keyword)
+	tt.DeepEq([]string{"x", "y&z"}, d.Tags)
+	tt.DeepEq([]uint16{1, 2}, d.IDs)
+	tt.Eq(0.5, *d.Ratio)
+	tt.True(d.Exact)
+	tt.True(d.Since.Equal(s.Since))
+	tt.True(d.Until.Equal(s.Until))
+	tt.Eq(s.Timeout, d.Timeout)
+	tt.Eq("keep", d.Internal)
+	tt.Eq("name", d.Sort)
+
+	tt.NNil(UnmarshalQuery("id=70000", &d, "query"))
+	tt.NNil(UnmarshalQuery("exact=yes", &d, "query"))
+	tt.Eq(ErrNonStructPointer, UnmarshalQuery("", d, "query"))
+	_, err = MarshalQuery(1, "query")
+	tt.Eq(ErrNonStruct, err)
+	_, err = MarshalQuery(struct{ M map[string]int }{map[string]int{}}, "query")
+	tt.NNil(err)
+
+	var ids struct {
+		IDs  *[]int `query:"ids"`
+		Data []byte `query:"data"`
+	}
+	query, err = MarshalQuery(ids, "query")
+	tt.Nil(err)
+	tt.Eq("data=", query)
+	tt.Nil(UnmarshalQuery("ids=1&ids=2&data=ab", &ids, "query"))
+	tt.DeepEq([]int{1, 2}, *ids.IDs)
+	tt.Eq("ab", string(ids.Data))
+	query, err = MarshalQuery(ids, "query")
+	tt.Nil(err)
+	tt.Eq("ids=1&ids=2&data=ab", query)
+	tt.NNil(UnmarshalQuery("ids=x", &ids, "query"))
+}
@@ -0,0 +1,345 @@
+package url2
+
+import (
+	"encoding"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cosiner/gohper/errors"
+)
+
+const (
+	ErrNonStruct        = errors.Err("url2: value is not a struct or pointer to struct")
+	ErrNonStructPointer = errors.Err("url2: value is not a pointer to struct")
+	ErrUnsupportedType  = errors.Err("url2: unsupported field type")
+)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	timeType            = reflect.TypeOf(time.Time{})
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// queryField is a struct field to encode, the tag is "name,omitempty,unix",
+// field is skipped if name is "-", if name is empty, lowercase field name is
+// used, same as reflect2.MarshalStruct
+type queryField struct {
+	name      string
+	omitempty bool
+	unix      bool
+}
+
+func parseQueryTag(field reflect.StructField, tag string) (queryField, bool) {
+	t := field.Tag.Get(tag)
+	if t == "-" {
+		return queryField{}, false
+	}
+
+	var f queryField
+	opts := strings.Split(t, ",")
+	f.name = opts[0]
+	if f.name == "" {
+		f.name = strings.ToLower(field.Name)
+	}
+	for _, opt := range opts[1:] {
+		switch opt {
+		case "omitempty":
+			f.omitempty = true
+		case "unix":
+			f.unix = true
+		}
+	}
+	return f, true
+}
+
+// MarshalQuery encode struct to query string, see AddStruct
+func MarshalQuery(v interface{}, tag string) (string, error) {
+	q := NewQuery()
+	if err := q.AddStruct(v, tag); err != nil {
+		return "", err
+	}
+	return q.Encode(), nil
+}
+
+// UnmarshalQuery decode query string to struct, see DecodeStruct
+func UnmarshalQuery(query string, v interface{}, tag string) error {
+	q, err := ParseQuery(query)
+	if err != nil {
+		return err
+	}
+	return q.DecodeStruct(v, tag)
+}
+
+// AddStruct add exported fields of the struct as parameters, tag is like
+// "name,omitempty,unix", name "-" means skip, empty name means lowercase
+// field name.
+//
+// Supported fields are primitives, time.Duration, time.Time(RFC3339, or unix
+// seconds with "unix" option), encoding.TextMarshaler, pointers and slices of
+// them, slices and pointers to slices are added as repeated keys, nil
+// pointers are skipped.
+// Anonymous struct fields are flattened.
+func (q *QueryBuilder) AddStruct(v interface{}, tag string) error {
+	value := reflect.ValueOf(v)
+	if value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return ErrNonStruct
+	}
+	return q.addStruct(value, tag)
+}
+
+func (q *QueryBuilder) addStruct(value reflect.Value, tag string) error {
+	typ := value.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		tfield, vfield := typ.Field(i), value.Field(i)
+		if tfield.Anonymous && indirectType(tfield.Type).Kind() == reflect.Struct &&
+			tfield.Tag.Get(tag) == "" && !isTextType(tfield.Type) {
+			if vfield.Kind() == reflect.Ptr {
+				if vfield.IsNil() {
+					continue
+				}
+				vfield = vfield.Elem()
+			}
+			if err := q.addStruct(vfield, tag); err != nil {
+				return err
+			}
+			continue
+		}
+		if !vfield.CanInterface() {
+			continue
+		}
+
+		f, ok := parseQueryTag(tfield, tag)
+		if !ok || (f.omitempty && isEmptyValue(vfield)) {
+			continue
+		}
+		if err := q.addField(f, vfield); err != nil {
+			return errors.Newf("url2: field %s: %s", tfield.Name, err.Error())
+		}
+	}
+	return nil
+}
+
+func (q *QueryBuilder) addField(f queryField, v reflect.Value) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if isRepeated(v.Type()) {
+		for i := 0; i < v.Len(); i++ {
+			if err := q.addField(f, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	s, err := formatValue(f, v)
+	if err == nil {
+		q.Add(f.name, s)
+	}
+	return err
+}
+
+func formatValue(f queryField, v reflect.Value) (string, error) {
+	switch {
+	case v.Type() == timeType && f.unix:
+		return strconv.FormatInt(v.Interface().(time.Time).Unix(), 10), nil
+	case v.Type() == durationType:
+		return v.Interface().(time.Duration).String(), nil
+	case v.Type().Implements(textMarshalerType):
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	case v.CanAddr() && v.Addr().Type().Implements(textMarshalerType):
+		text, err := v.Addr().Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes()), nil
+		}
+	}
+	return "", ErrUnsupportedType
+}
+
+// DecodeStruct decode parameters to struct fields, v must be a pointer to
+// struct, fields without parameter are not changed, slices are replaced by
+// all values of the key. Tag and supported fields are same as AddStruct.
+func (q *QueryBuilder) DecodeStruct(v interface{}, tag string) error {
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return ErrNonStructPointer
+	}
+	return q.decodeStruct(value.Elem(), tag)
+}
+
+func (q *QueryBuilder) decodeStruct(value reflect.Value, tag string) error {
+	typ := value.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		tfield, vfield := typ.Field(i), value.Field(i)
+		if tfield.Anonymous && indirectType(tfield.Type).Kind() == reflect.Struct &&
+			tfield.Tag.Get(tag) == "" && !isTextType(tfield.Type) {
+			if vfield.Kind() == reflect.Ptr {
+				if !vfield.CanSet() {
+					continue
+				}
+				if vfield.IsNil() {
+					vfield.Set(reflect.New(tfield.Type.Elem()))
+				}
+				vfield = vfield.Elem()
+			}
+			if err := q.decodeStruct(vfield, tag); err != nil {
+				return err
+			}
+			continue
+		}
+		if !vfield.CanSet() {
+			continue
+		}
+
+		f, ok := parseQueryTag(tfield, tag)
+		if !ok || !q.Has(f.name) {
+			continue
+		}
+		if err := decodeField(f, q.Values(f.name), vfield); err != nil {
+			return errors.Newf("url2: field %s: %s", tfield.Name, err.Error())
+		}
+	}
+	return nil
+}
+
+func decodeField(f queryField, values []string, v reflect.Value) error {
+	if v.Kind() == reflect.Ptr {
+		elem := reflect.New(v.Type().Elem())
+		if err := decodeField(f, values, elem.Elem()); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+	if isRepeated(v.Type()) {
+		s := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := decodeField(f, []string{value}, s.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	}
+	return parseValue(f, values[0], v)
+}
+
+func parseValue(f queryField, s string, v reflect.Value) error {
+	switch {
+	case v.Type() == timeType && f.unix:
+		sec, err := strconv.ParseInt(s, 10, 64)
+		if err == nil {
+			v.Set(reflect.ValueOf(time.Unix(sec, 0)))
+		}
+		return err
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err == nil {
+			v.SetInt(int64(d))
+		}
+		return err
+	case v.Addr().Type().Implements(textUnmarshalerType):
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			return ErrUnsupportedType
+		}
+		v.SetBytes([]byte(s))
+	default:
+		return ErrUnsupportedType
+	}
+	return nil
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
+
+// isRepeated report whether values of type are added as repeated keys, it's
+// true for slices except []byte and text types
+func isRepeated(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 && !isTextType(t)
+}
+
+func isTextType(t reflect.Type) bool {
+	return t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType)
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return v.Interface().(time.Time).IsZero()
+		}
+	}
+	return false
+}
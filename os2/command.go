@@ -0,0 +1,354 @@
+package os2
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/cosiner/gohper/errors"
+)
+
+const ErrTimeout = errors.Err("command timeout")
+
+// Cmd is a command builder, it support timeout, environment control, output
+// capture and pipeline
+type Cmd struct {
+	args     []string
+	dir      string
+	env      []string
+	clearEnv bool
+
+	stdin          io.Reader
+	stdout, stderr io.Writer
+	combined       bool
+	limit          int
+
+	ctx     context.Context
+	timeout time.Duration
+
+	pipes []*Cmd
+}
+
+// Result is the result of a command, for pipeline, it's the result of last
+// command, and Stages contains results of all commands
+type Result struct {
+	Args []string
+	// ExitCode is -1 if the process was not exited normally, such as killed
+	// by signal
+	ExitCode int
+	Duration time.Duration
+	// Stdout is the captured output, it contains both stdout and stderr if
+	// output are combined
+	Stdout []byte
+	Stderr []byte
+	// Truncated report whether output exceeds the limit and was truncated
+	Truncated bool
+	TimedOut  bool
+
+	Stages []*Result
+}
+
+// NewCmd create a command, first element of args is the command to execute
+func NewCmd(args ...string) *Cmd {
+	if len(args) == 0 {
+		panic("no command to run")
+	}
+
+	return &Cmd{args: args}
+}
+
+// Dir set working directory
+func (c *Cmd) Dir(dir string) *Cmd {
+	c.dir = dir
+	return c
+}
+
+// Env add environment variables in "KEY=VALUE" form, they override the
+// inherited ones
+func (c *Cmd) Env(env ...string) *Cmd {
+	c.env = append(c.env, env...)
+	return c
+}
+
+// ClearEnv don't inherit environment variables of current process
+func (c *Cmd) ClearEnv() *Cmd {
+	c.clearEnv = true
+	return c
+}
+
+func (c *Cmd) Stdin(r io.Reader) *Cmd {
+	c.stdin = r
+	return c
+}
+
+// Tee also write output to the writers while capturing, nil writer is ignored,
+// writes to them are serialized, so a writer can be used for both
+func (c *Cmd) Tee(stdout, stderr io.Writer) *Cmd {
+	c.stdout, c.stderr = stdout, stderr
+	return c
+}
+
+// Combined capture stdout and stderr to the same buffer in order, the output
+// is also written to stdout writer of Tee, the stderr writer is not used
+func (c *Cmd) Combined() *Cmd {
+	c.combined = true
+	return c
+}
+
+// Limit the captured size of stdout and stderr each, extra output is dropped,
+// 0 means no limit
+func (c *Cmd) Limit(n int) *Cmd {
+	c.limit = n
+	return c
+}
+
+// Context set the context, the process group is killed if it's done
+func (c *Cmd) Context(ctx context.Context) *Cmd {
+	c.ctx = ctx
+	return c
+}
+
+// Timeout set the deadline of running, the process group is killed if timeout
+func (c *Cmd) Timeout(d time.Duration) *Cmd {
+	c.timeout = d
+	return c
+}
+
+// Pipe connect stdout of previous command to stdin of next command, like
+// shell "c | cmds[0] | cmds[1]". Stdin, output, timeout and context settings
+// of c apply to the whole pipeline, settings of other commands are ignored
+func (c *Cmd) Pipe(cmds ...*Cmd) *Cmd {
+	c.pipes = append(c.pipes, cmds...)
+	return c
+}
+
+func (c *Cmd) environ() []string {
+	var env []string
+	if !c.clearEnv {
+		env = os.Environ()
+	}
+
+	return append(env, c.env...) // later one take precedence
+}
+
+// limitBuffer don't embed bytes.Buffer, otherwise io.Copy will use it's
+// ReadFrom method and bypass the limit
+type limitBuffer struct {
+	buf       bytes.Buffer
+	lock      sync.Mutex // stdout and stderr may write concurrently if combined
+	limit     int
+	truncated bool
+	tee       io.Writer
+}
+
+func newLimitBuffer(limit int, tee io.Writer) *limitBuffer {
+	return &limitBuffer{
+		limit: limit,
+		tee:   tee,
+	}
+}
+
+func (b *limitBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.tee != nil {
+		b.tee.Write(p)
+	}
+
+	n := len(p)
+	if b.limit > 0 {
+		remain := b.limit - b.buf.Len()
+		if remain < 0 {
+			remain = 0
+		}
+		if remain < n {
+			p = p[:remain]
+			b.truncated = true
+		}
+	}
+	b.buf.Write(p)
+
+	return n, nil
+}
+
+// Run execute the command or pipeline, wait it to complete. The returned
+// error is ErrTimeout if timeout, context's error if context is done, or the
+// error of rightmost failed command in the pipeline, result is always non-nil.
+func (c *Cmd) Run() (*Result, error) {
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	var (
+		stages  = append([]*Cmd{c}, c.pipes...)
+		n       = len(stages)
+		cmds    = make([]*exec.Cmd, n)
+		results = make([]*Result, n)
+		stderrs = make([]*limitBuffer, n)
+		teeLock sync.Mutex
+		teeOut  = newSyncWriter(&teeLock, c.stdout)
+		teeErr  = newSyncWriter(&teeLock, c.stderr)
+		stdout  = newLimitBuffer(c.limit, teeOut)
+		stdin   = c.stdin
+	)
+	for i, s := range stages {
+		cmd := exec.Command(s.args[0], s.args[1:]...)
+		cmd.Dir = s.dir
+		cmd.Env = s.environ()
+		cmd.Stdin = stdin
+		setProcessGroup(cmd)
+
+		stderrs[i] = newLimitBuffer(c.limit, teeErr)
+		cmd.Stderr = stderrs[i]
+		if i == n-1 {
+			cmd.Stdout = stdout
+			if c.combined {
+				// same writer make exec share one pipe, so the order of
+				// output is kept
+				cmd.Stderr = stdout
+			}
+		}
+
+		cmds[i] = cmd
+		results[i] = &Result{
+			Args:     s.args,
+			ExitCode: -1,
+		}
+	}
+
+	start := time.Now()
+	started, err := startPipeline(cmds)
+	if err != nil {
+		// started commands are waited below
+		for _, cmd := range cmds[:started] {
+			killProcessGroup(cmd.Process)
+		}
+	}
+
+	done, killed := make(chan struct{}), make(chan bool, 1)
+	go func() {
+		select {
+		case <-ctx.Done():
+			for _, cmd := range cmds[:started] {
+				killProcessGroup(cmd.Process)
+			}
+			killed <- true
+		case <-done:
+			killed <- false
+		}
+	}()
+
+	var waitErr error
+	for i, cmd := range cmds[:started] {
+		e := cmd.Wait()
+		if e != nil {
+			waitErr = e // rightmost failed command
+		}
+
+		r := results[i]
+		r.Duration = time.Since(start)
+		r.ExitCode = cmd.ProcessState.ExitCode()
+		r.Stderr = stderrs[i].buf.Bytes()
+		r.Truncated = stderrs[i].truncated
+	}
+	close(done)
+	// commands may have exited before the kill, then the context is not the
+	// cause of error
+	byCtx := <-killed && waitErr != nil
+
+	res := results[n-1]
+	res.Stdout = stdout.buf.Bytes()
+	res.Truncated = res.Truncated || stdout.truncated
+	res.Duration = time.Since(start)
+	if n > 1 {
+		res.Stages = results
+	}
+
+	if err == nil {
+		err = waitErr
+	}
+	if byCtx {
+		e := ctx.Err()
+		if e == context.DeadlineExceeded {
+			e = ErrTimeout
+			for _, r := range results {
+				r.TimedOut = true
+			}
+		}
+		err = e
+	}
+
+	return res, err
+}
+
+// startPipeline start commands and connect them with pipes, return the number
+// of started commands. On error, pipes of the rest commands are closed, the
+// started commands should be killed and waited by caller.
+func startPipeline(cmds []*exec.Cmd) (int, error) {
+	for i, cmd := range cmds {
+		var pr, pw *os.File
+		if i < len(cmds)-1 {
+			var err error
+			if pr, pw, err = os.Pipe(); err != nil {
+				if i > 0 {
+					cmd.Stdin.(*os.File).Close()
+				}
+				return i, err
+			}
+			cmd.Stdout = pw
+			cmds[i+1].Stdin = pr
+		}
+
+		err := cmd.Start()
+		if pw != nil {
+			pw.Close() // the child process hold it's own copy
+		}
+		if i > 0 {
+			cmd.Stdin.(*os.File).Close()
+		}
+		if err != nil {
+			if pr != nil {
+				pr.Close()
+			}
+			return i, err
+		}
+	}
+
+	return len(cmds), nil
+}
+
+// syncWriter serialize writes to writers of Tee, they are written by the
+// goroutines copying stdout and stderr of each command
+type syncWriter struct {
+	lock *sync.Mutex
+	w    io.Writer
+}
+
+func newSyncWriter(lock *sync.Mutex, w io.Writer) io.Writer {
+	if w == nil {
+		return nil
+	}
+
+	return syncWriter{
+		lock: lock,
+		w:    w,
+	}
+}
+
+func (w syncWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return w.w.Write(p)
+}
@@ -0,0 +1,81 @@
+package os2
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cosiner/gohper/testing2"
+)
+
+func TestCmd(t *testing.T) {
+	if IsWindows() {
+		return
+	}
+	tt := testing2.Wrap(t)
+
+	res, err := NewCmd("sh", "-c", "echo $GOHPER_A; echo err >&2; exit 3").
+		Env("GOHPER_A=a").
+		Run()
+	tt.NNil(err)
+	tt.Eq(3, res.ExitCode)
+	tt.Eq("a\n", string(res.Stdout))
+	tt.Eq("err\n", string(res.Stderr))
+
+	res, err = NewCmd("pwd").Dir("/").Run()
+	tt.Nil(err)
+	tt.Eq("/\n", string(res.Stdout))
+
+	var tee bytes.Buffer
+	res, err = NewCmd("sh", "-c", "echo 1234567890; echo abc >&2").
+		Combined().
+		Limit(4).
+		Tee(&tee, nil).
+		Run()
+	tt.Nil(err)
+	tt.Eq("1234", string(res.Stdout))
+	tt.True(res.Truncated)
+	tt.Eq("1234567890\nabc\n", tee.String())
+
+	tee.Reset()
+	_, err = NewCmd("sh", "-c", "echo out; echo err >&2").
+		Tee(&tee, &tee).
+		Run()
+	tt.Nil(err)
+	tt.Eq(8, tee.Len())
+
+	res, err = NewCmd("printf", "b\\na\\nc\\n").
+		Pipe(NewCmd("sort"), NewCmd("head", "-n", "2")).
+		Run()
+	tt.Nil(err)
+	tt.Eq("a\nb\n", string(res.Stdout))
+	tt.Eq(3, len(res.Stages))
+
+	_, err = NewCmd("sh", "-c", "exit 1").Pipe(NewCmd("cat")).Run()
+	tt.NNil(err)
+
+	res, err = NewCmd("cat").Stdin(strings.NewReader("in")).Run()
+	tt.Nil(err)
+	tt.Eq("in", string(res.Stdout))
+}
+
+func TestCmdTimeout(t *testing.T) {
+	if IsWindows() {
+		return
+	}
+	tt := testing2.Wrap(t)
+
+	begin := time.Now()
+	res, err := NewCmd("sh", "-c", "sleep 10 & sleep 10").Timeout(100 * time.Millisecond).Run()
+	tt.Eq(ErrTimeout, err)
+	tt.True(res.TimedOut)
+	tt.Eq(-1, res.ExitCode)
+	tt.True(time.Since(begin) < 5*time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = NewCmd("sleep", "10").Context(ctx).Run()
+	tt.Eq(context.Canceled, err)
+}
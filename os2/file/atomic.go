@@ -0,0 +1,106 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Atomic write file by write to a temporary file in the same directory, then
+// sync and rename it to the destination, so readers will see either the old
+// or the new content, never a partial one
+type Atomic struct {
+	// Perm is used if the file doesn't exist, otherwise the permission of
+	// existing file is kept, default FilePerm
+	Perm os.FileMode
+	// BackupSuffix, if not empty, the previous version of file will be kept
+	// as fname+BackupSuffix
+	BackupSuffix string
+}
+
+// AtomicWrite write file atomically with default options
+func AtomicWrite(fname string, fn FileOpFunc) error {
+	return Atomic{}.Write(fname, fn)
+}
+
+// AtomicOverwrite replace all content of file atomically
+func AtomicOverwrite(fname string, content string) error {
+	return AtomicWrite(fname, func(fd *os.File) error {
+		_, err := fd.WriteString(content)
+
+		return err
+	})
+}
+
+func (a Atomic) Write(fname string, fn FileOpFunc) (err error) {
+	if real, err := filepath.EvalSymlinks(fname); err == nil {
+		fname = real // replace the link target instead of the link itself
+	}
+
+	perm := a.Perm
+	if perm == 0 {
+		perm = FilePerm
+	}
+	fi, err := os.Stat(fname)
+	if err == nil {
+		perm = fi.Mode().Perm()
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	dir, base := filepath.Split(fname)
+	if dir == "" {
+		dir = "."
+	}
+	fd, err := ioutil.TempFile(dir, "."+base+".tmp")
+	if err != nil {
+		return err
+	}
+
+	tmp := fd.Name()
+	defer func() {
+		if err != nil {
+			os.Remove(tmp)
+		}
+	}()
+
+	if fn != nil {
+		err = fn(fd)
+	}
+	if err == nil {
+		err = fd.Chmod(perm)
+	}
+	if err == nil {
+		err = fd.Sync()
+	}
+	if e := fd.Close(); e != nil && err == nil {
+		err = e
+	}
+	if err != nil {
+		return err
+	}
+
+	if a.BackupSuffix != "" && fi != nil {
+		if err = backup(fname, fname+a.BackupSuffix); err != nil {
+			return err
+		}
+	}
+	if err = os.Rename(tmp, fname); err != nil {
+		return err
+	}
+
+	return syncDir(dir)
+}
+
+// backup keep the current version of file, hard link is preferred, fallback
+// to copy if failed
+func backup(fname, bak string) error {
+	if err := os.Remove(bak); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if os.Link(fname, bak) == nil {
+		return nil
+	}
+
+	return copyRaw(bak, fname)
+}
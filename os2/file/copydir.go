@@ -0,0 +1,382 @@
+package file
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// SymlinkPolicy decide how to process symbolic links when copying directory
+type SymlinkPolicy uint8
+
+const (
+	// SymlinkFollow copy the file or directory the link point to
+	SymlinkFollow SymlinkPolicy = iota
+	// SymlinkPreserve create a same link in destination
+	SymlinkPreserve
+	// SymlinkSkip ignore links
+	SymlinkSkip
+)
+
+// CompareMode decide how to check whether a file is unchanged and can be skipped
+type CompareMode uint8
+
+const (
+	// CompareNone always copy file
+	CompareNone CompareMode = iota
+	// CompareSizeTime skip file if size and modify time is same, it's usually
+	// used with PreserveTime
+	CompareSizeTime
+	// CompareChecksum skip file if size and sha256 checksum is same
+	CompareChecksum
+)
+
+// CopyProgress is reported after each file is copied or skipped
+type CopyProgress struct {
+	Path    string // path relative to source directory
+	Size    int64
+	Skipped bool
+	Done    int // number of processed files
+	Total   int
+}
+
+type CopyOptions struct {
+	// Include patterns, only files match any of them will be copied, empty
+	// means all. Patterns are in filepath.Match syntax, a pattern contains
+	// '/' is matched with the slash separated relative path, otherwise the
+	// base name
+	Include []string
+	// Exclude patterns, matched files and directories are skipped
+	Exclude []string
+
+	Symlink      SymlinkPolicy
+	PreserveMode bool
+	PreserveTime bool
+	Compare      CompareMode
+
+	// Progress is called sequentially, even if copy parallel
+	Progress func(CopyProgress)
+	// Parallel is the maximum number of files copying concurrently, default 1
+	Parallel int
+}
+
+// CopyDir copy directory from source to destination, symbolic links are followed
+func CopyDir(dst, src string) error {
+	return CopyOptions{}.CopyDir(dst, src)
+}
+
+type copyJob struct {
+	rel      string
+	src, dst string
+	info     os.FileInfo
+}
+
+type dirInfo struct {
+	path string
+	info os.FileInfo
+}
+
+func (o CopyOptions) CopyDir(dst, src string) error {
+	c := copier{
+		CopyOptions: o,
+		visited:     make(map[string]bool),
+	}
+	err := c.walk(dst, src, "")
+	if err != nil {
+		return err
+	}
+
+	if err = c.copyFiles(); err != nil {
+		return err
+	}
+
+	// after contents are copied, a read-only mode or new files won't break it
+	for i := len(c.dirs) - 1; i >= 0 && err == nil; i-- { // children first
+		d := c.dirs[i]
+		if !IsExist(d.path) {
+			continue // nothing included
+		}
+		if o.PreserveMode {
+			err = os.Chmod(d.path, d.info.Mode().Perm())
+		}
+		if err == nil && o.PreserveTime {
+			err = os.Chtimes(d.path, d.info.ModTime(), d.info.ModTime())
+		}
+	}
+
+	return err
+}
+
+type copier struct {
+	CopyOptions
+
+	jobs    []copyJob
+	dirs    []dirInfo
+	visited map[string]bool // real path of current ancestors, avoid link loop
+
+	lock sync.Mutex
+	done int
+}
+
+func (c *copier) match(patterns []string, rel string) bool {
+	rel = filepath.ToSlash(rel)
+	base := filepath.Base(rel)
+	for _, p := range patterns {
+		name := base
+		if strings.Contains(p, "/") {
+			name = rel
+		}
+		if matched, _ := filepath.Match(p, name); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *copier) mkdir(dst string) error {
+	fi, err := os.Stat(dst)
+	if err == nil && !fi.IsDir() {
+		return ErrDestIsFile
+	}
+
+	return os.MkdirAll(dst, DirPerm)
+}
+
+func (c *copier) walk(dst, src, rel string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if real, err := filepath.EvalSymlinks(src); err == nil {
+		if c.visited[real] {
+			return nil // link to an ancestor
+		}
+		c.visited[real] = true
+		defer delete(c.visited, real)
+	}
+
+	// with Include, directories are created when copying files
+	if rel == "" || len(c.Include) == 0 {
+		if err = c.mkdir(dst); err != nil {
+			return err
+		}
+	}
+	if c.PreserveMode || c.PreserveTime {
+		c.dirs = append(c.dirs, dirInfo{path: dst, info: info})
+	}
+
+	files, err := ioutil.ReadDir(src)
+	if err != nil {
+		return err
+	}
+
+	for _, fi := range files {
+		name := fi.Name()
+		frel := filepath.Join(rel, name)
+		if c.match(c.Exclude, frel) {
+			continue
+		}
+
+		sf, df := filepath.Join(src, name), filepath.Join(dst, name)
+		if fi.Mode()&os.ModeSymlink != 0 {
+			switch c.Symlink {
+			case SymlinkSkip:
+				continue
+			case SymlinkPreserve:
+				if len(c.Include) == 0 || c.match(c.Include, frel) {
+					c.jobs = append(c.jobs, copyJob{rel: frel, src: sf, dst: df, info: fi})
+				}
+				continue
+			}
+
+			if fi, err = os.Stat(sf); err != nil {
+				return err
+			}
+		}
+
+		if fi.IsDir() {
+			err = c.walk(df, sf, frel)
+			if err != nil {
+				return err
+			}
+		} else if fi.Mode().IsRegular() && (len(c.Include) == 0 || c.match(c.Include, frel)) {
+			c.jobs = append(c.jobs, copyJob{rel: frel, src: sf, dst: df, info: fi})
+		}
+	}
+
+	return nil
+}
+
+func (c *copier) copyFiles() error {
+	parallel := c.Parallel
+	if parallel <= 0 {
+		parallel = 1
+	}
+	if parallel > len(c.jobs) {
+		parallel = len(c.jobs)
+	}
+
+	var (
+		jobs = make(chan copyJob)
+		errs = make(chan error, parallel)
+		stop = make(chan struct{})
+		wg   sync.WaitGroup
+	)
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				if err := c.copy(job); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+
+	var err error
+	go func() {
+		wg.Wait()
+		close(stop)
+	}()
+	for i := 0; i < len(c.jobs) && err == nil; i++ {
+		select {
+		case jobs <- c.jobs[i]:
+		case err = <-errs:
+		}
+	}
+	close(jobs)
+	<-stop
+	if err == nil && len(errs) > 0 {
+		err = <-errs
+	}
+
+	return err
+}
+
+func (c *copier) copy(job copyJob) error {
+	var (
+		skipped bool
+		err     error
+	)
+	if len(c.Include) != 0 {
+		err = os.MkdirAll(filepath.Dir(job.dst), DirPerm)
+	}
+	if err == nil {
+		if job.info.Mode()&os.ModeSymlink != 0 {
+			err = copyLink(job.dst, job.src)
+		} else if skipped, err = c.unchanged(job); err == nil && !skipped {
+			err = c.copyFile(job)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if c.Progress != nil {
+		c.lock.Lock()
+		c.done++
+		c.Progress(CopyProgress{
+			Path:    job.rel,
+			Size:    job.info.Size(),
+			Skipped: skipped,
+			Done:    c.done,
+			Total:   len(c.jobs),
+		})
+		c.lock.Unlock()
+	}
+
+	return nil
+}
+
+func (c *copier) unchanged(job copyJob) (bool, error) {
+	if c.Compare == CompareNone {
+		return false, nil
+	}
+
+	fi, err := os.Stat(job.dst)
+	if err != nil || !fi.Mode().IsRegular() || fi.Size() != job.info.Size() {
+		return false, nil
+	}
+
+	switch c.Compare {
+	case CompareSizeTime:
+		return fi.ModTime().Equal(job.info.ModTime()), nil
+	case CompareChecksum:
+		s1, err := Checksum(job.src)
+		if err != nil {
+			return false, err
+		}
+		s2, err := Checksum(job.dst)
+		if err != nil {
+			return false, err
+		}
+
+		return bytes.Equal(s1, s2), nil
+	}
+
+	return false, nil
+}
+
+func (c *copier) copyFile(job copyJob) error {
+	perm := os.FileMode(FilePerm)
+	if c.PreserveMode {
+		perm = job.info.Mode().Perm()
+	}
+
+	err := Read(job.src, func(sfd *os.File) error {
+		dfd, err := os.OpenFile(job.dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(dfd, sfd)
+		if err == nil && c.PreserveMode {
+			err = dfd.Chmod(perm) // file may already exist or affected by umask
+		}
+		if e := dfd.Close(); e != nil && err == nil {
+			err = e
+		}
+
+		return err
+	})
+	if err == nil && c.PreserveTime {
+		err = os.Chtimes(job.dst, job.info.ModTime(), job.info.ModTime())
+	}
+
+	return err
+}
+
+func copyLink(dst, src string) error {
+	target, err := os.Readlink(src)
+	if err != nil {
+		return err
+	}
+
+	if err = os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return os.Symlink(target, dst)
+}
+
+// Checksum return sha256 checksum of file content
+func Checksum(fname string) ([]byte, error) {
+	var sum []byte
+	err := Read(fname, func(fd *os.File) error {
+		h := sha256.New()
+		_, err := io.Copy(h, fd)
+		sum = h.Sum(nil)
+
+		return err
+	})
+
+	return sum, err
+}
@@ -1,9 +1,14 @@
 package file
 
 import (
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
+	"time"
 
+	"github.com/cosiner/gohper/errors"
 	"github.com/cosiner/gohper/testing2"
 )
 
@@ -24,3 +29,137 @@ func TestWriteFlag(t *testing.T) {
 	tt.Eq(os.O_APPEND, WriteFlag(false))
 	tt.Eq(os.O_TRUNC, WriteFlag(true))
 }
+
+func TestAtomicWrite(t *testing.T) {
+	tt := testing2.Wrap(t)
+
+	dir, err := ioutil.TempDir("", "atomic")
+	tt.Nil(err)
+	defer os.RemoveAll(dir)
+
+	fname := filepath.Join(dir, "conf")
+	tt.Nil(AtomicOverwrite(fname, "v1"))
+	tt.Nil(os.Chmod(fname, 0600))
+
+	a := Atomic{BackupSuffix: ".bak"}
+	tt.Nil(a.Write(fname, func(fd *os.File) error {
+		_, err := fd.WriteString("v2")
+		return err
+	}))
+
+	data, _ := ioutil.ReadFile(fname)
+	tt.Eq("v2", string(data))
+	data, _ = ioutil.ReadFile(fname + ".bak")
+	tt.Eq("v1", string(data))
+	fi, err := os.Stat(fname)
+	tt.Nil(err)
+	tt.Eq(os.FileMode(0600), fi.Mode().Perm())
+
+	errWrite := errors.Err("write failed")
+	tt.Eq(errWrite, AtomicWrite(fname, func(*os.File) error {
+		return errWrite
+	}))
+	data, _ = ioutil.ReadFile(fname)
+	tt.Eq("v2", string(data))
+
+	files, _ := ioutil.ReadDir(dir)
+	tt.Eq(2, len(files))
+}
+
+func TestLock(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	tt := testing2.Wrap(t)
+
+	fname := filepath.Join(os.TempDir(), "gohper.lock")
+	defer os.Remove(fname)
+
+	l1, err := NewLock(fname)
+	tt.Nil(err)
+	defer l1.Close()
+	l2, err := NewLock(fname)
+	tt.Nil(err)
+	defer l2.Close()
+
+	tt.Nil(l1.Lock())
+	locked, err := l2.TryLock()
+	tt.Nil(err).False(locked)
+	tt.Nil(l1.Unlock())
+
+	locked, err = l2.TryRLock()
+	tt.Nil(err).True(locked)
+	locked, err = l1.TryRLock()
+	tt.Nil(err).True(locked)
+}
+
+func TestCopyDir(t *testing.T) {
+	tt := testing2.Wrap(t)
+
+	dir, err := ioutil.TempDir("", "copydir")
+	tt.Nil(err)
+	defer os.RemoveAll(dir)
+
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	tt.Nil(os.MkdirAll(filepath.Join(src, "lib", "tmp"), DirPerm))
+	tt.Nil(ioutil.WriteFile(filepath.Join(src, "main"), []byte("main"), 0755))
+	tt.Nil(ioutil.WriteFile(filepath.Join(src, "lib", "a.so"), []byte("a"), FilePerm))
+	tt.Nil(ioutil.WriteFile(filepath.Join(src, "lib", "a.o"), []byte("a"), FilePerm))
+	tt.Nil(ioutil.WriteFile(filepath.Join(src, "lib", "tmp", "b.so"), []byte("b"), FilePerm))
+	tt.Nil(os.Symlink("a.so", filepath.Join(src, "lib", "link.so")))
+	libTime := time.Unix(1400000000, 0)
+	tt.Nil(os.Chmod(filepath.Join(src, "lib"), 0750))
+	tt.Nil(os.Chtimes(filepath.Join(src, "lib"), libTime, libTime))
+
+	var progress []CopyProgress
+	opts := CopyOptions{
+		Include:      []string{"main", "*.so"},
+		Exclude:      []string{"lib/tmp"},
+		Symlink:      SymlinkPreserve,
+		PreserveMode: true,
+		PreserveTime: true,
+		Compare:      CompareSizeTime,
+		Parallel:     4,
+		Progress: func(p CopyProgress) {
+			progress = append(progress, p)
+		},
+	}
+	tt.Nil(opts.CopyDir(dst, src))
+	tt.Eq(3, len(progress))
+	tt.True(IsFile(filepath.Join(dst, "lib", "a.so")))
+	tt.False(IsExist(filepath.Join(dst, "lib", "a.o")))
+	tt.False(IsExist(filepath.Join(dst, "lib", "tmp")))
+	tt.True(IsSymlink(filepath.Join(dst, "lib", "link.so")))
+
+	fi, err := os.Stat(filepath.Join(dst, "main"))
+	tt.Nil(err)
+	tt.Eq(os.FileMode(0755), fi.Mode().Perm())
+	fi, err = os.Stat(filepath.Join(dst, "lib"))
+	tt.Nil(err)
+	tt.Eq(os.FileMode(0750), fi.Mode().Perm())
+	tt.True(fi.ModTime().Equal(libTime))
+
+	progress = nil
+	tt.Nil(opts.CopyDir(dst, src))
+	for _, p := range progress {
+		if p.Path != filepath.Join("lib", "link.so") {
+			tt.True(p.Skipped)
+		}
+	}
+
+	opts = CopyOptions{Symlink: SymlinkSkip, Compare: CompareChecksum}
+	dst = filepath.Join(dir, "all")
+	tt.Nil(opts.CopyDir(dst, src))
+	tt.True(IsFile(filepath.Join(dst, "lib", "tmp", "b.so")))
+	tt.False(IsExist(filepath.Join(dst, "lib", "link.so")))
+
+	// sibling links to a same directory are not loop
+	tt.Nil(os.Symlink("lib", filepath.Join(src, "lib1")))
+	tt.Nil(os.Symlink("lib", filepath.Join(src, "lib2")))
+	tt.Nil(os.Symlink("..", filepath.Join(src, "lib", "up")))
+	dst = filepath.Join(dir, "follow")
+	tt.Nil(CopyOptions{Exclude: []string{"link.so"}}.CopyDir(dst, src))
+	tt.True(IsFile(filepath.Join(dst, "lib1", "a.so")))
+	tt.True(IsFile(filepath.Join(dst, "lib2", "a.so")))
+	tt.False(IsExist(filepath.Join(dst, "lib", "up")))
+}
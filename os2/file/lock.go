@@ -0,0 +1,78 @@
+package file
+
+import (
+	"os"
+
+	"github.com/cosiner/gohper/errors"
+)
+
+const ErrLockUnsupported = errors.Err("file lock is not supported on this platform")
+
+// Lock is a advisory file lock based on flock, it's used to coordinate
+// processes, not goroutines in the same process
+type Lock struct {
+	fd *os.File
+}
+
+// NewLock open or create the lock file
+func NewLock(fname string) (*Lock, error) {
+	fd, err := os.OpenFile(fname, os.O_RDWR|os.O_CREATE, FilePerm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Lock{fd: fd}, nil
+}
+
+// Lock acquire an exclusive lock, blocked until success
+func (l *Lock) Lock() error {
+	_, err := flock(l.fd, true, true)
+
+	return err
+}
+
+// RLock acquire a shared lock, blocked until success
+func (l *Lock) RLock() error {
+	_, err := flock(l.fd, false, true)
+
+	return err
+}
+
+// TryLock try to acquire an exclusive lock, return false if it's held by others
+func (l *Lock) TryLock() (bool, error) {
+	return flock(l.fd, true, false)
+}
+
+// TryRLock try to acquire a shared lock, return false if a exclusive lock is
+// held by others
+func (l *Lock) TryRLock() (bool, error) {
+	return flock(l.fd, false, false)
+}
+
+func (l *Lock) Unlock() error {
+	return funlock(l.fd)
+}
+
+// Close release the lock and close the lock file
+func (l *Lock) Close() error {
+	return l.fd.Close()
+}
+
+// WithLock run fn with an exclusive lock of lock file
+func WithLock(fname string, fn func() error) error {
+	l, err := NewLock(fname)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	if err = l.Lock(); err != nil {
+		return err
+	}
+	err = fn()
+	if e := l.Unlock(); e != nil && err == nil {
+		err = e
+	}
+
+	return err
+}
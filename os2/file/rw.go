@@ -2,9 +2,7 @@ package file
 
 import (
 	"io"
-	"io/ioutil"
 	"os"
-	"path/filepath"
 
 	"github.com/cosiner/gohper/errors"
 	"github.com/cosiner/gohper/io2"
@@ -74,27 +72,25 @@ func Copy(dst, src string) error {
 	return FilterTo(dst, src, true, nil)
 }
 
-// CopyDir copy directory from source to destination
-func CopyDir(dst, src string) error {
-	err := os.MkdirAll(dst, 0755)
-	if err != nil {
-		return err
-	}
-
-	files, err := ioutil.ReadDir(src)
+// copyRaw copy file content as is, the permission of src is kept
+func copyRaw(dst, src string) error {
+	return Read(src, func(sfd *os.File) error {
+		fi, err := sfd.Stat()
+		if err != nil {
+			return err
+		}
 
-	for i := 0; i < len(files) && err == nil; i++ {
-		file := files[i].Name()
-		df := filepath.Join(dst, file)
-		sf := filepath.Join(src, file)
-		if IsFile(sf) {
-			err = Copy(df, sf)
-		} else {
-			err = CopyDir(df, sf)
+		dfd, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, fi.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(dfd, sfd)
+		if e := dfd.Close(); e != nil && err == nil {
+			err = e
 		}
-	}
 
-	return err
+		return err
+	})
 }
 
 // Overwrite delete all content in file, and write new content to it
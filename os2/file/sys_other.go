@@ -0,0 +1,18 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package file
+
+import "os"
+
+func flock(*os.File, bool, bool) (bool, error) {
+	return false, ErrLockUnsupported
+}
+
+func funlock(*os.File) error {
+	return ErrLockUnsupported
+}
+
+// syncDir do nothing, directories can't be synced portably on these platforms
+func syncDir(string) error {
+	return nil
+}
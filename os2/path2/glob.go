@@ -0,0 +1,49 @@
+package path2
+
+import (
+	"path"
+	"strings"
+)
+
+// Match report whether slash separated name matches the pattern, pattern is
+// in path.Match syntax, and a "**" segment matches zero or more directories
+func Match(pattern, name string) bool {
+	return matchSegments(splitSlash(pattern), splitSlash(name))
+}
+
+func splitSlash(s string) []string {
+	s = strings.Trim(s, "/")
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(s, "/")
+}
+
+func matchSegments(pats, names []string) bool {
+	for len(pats) > 0 {
+		p := pats[0]
+		if p == "**" {
+			for len(pats) > 1 && pats[1] == "**" {
+				pats = pats[1:]
+			}
+			for i := 0; i <= len(names); i++ {
+				if matchSegments(pats[1:], names[i:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(names) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(p, names[0]); !matched {
+			return false
+		}
+		pats, names = pats[1:], names[1:]
+	}
+
+	return len(names) == 0
+}
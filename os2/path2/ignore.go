@@ -0,0 +1,148 @@
+package path2
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+)
+
+type ignorePattern struct {
+	base     string // slash separated directory of pattern file, relative to walk root
+	segs     []string
+	negate   bool
+	dirOnly  bool
+	anywhere bool // pattern without slash matches at any level
+}
+
+// Ignore is a list of patterns in .gitignore syntax, later pattern take
+// precedence over earlier ones
+type Ignore struct {
+	patterns []ignorePattern
+}
+
+// NewIgnore create Ignore with patterns relative to the root
+func NewIgnore(patterns ...string) *Ignore {
+	ig := &Ignore{}
+	ig.Add("", patterns...)
+
+	return ig
+}
+
+// Add patterns of a pattern file located at base directory, base is
+// slash separated and relative to the root
+func (ig *Ignore) Add(base string, patterns ...string) {
+	base = strings.Trim(base, "/")
+	for _, line := range patterns {
+		if p, ok := parseIgnorePattern(base, line); ok {
+			ig.patterns = append(ig.patterns, p)
+		}
+	}
+}
+
+// AddFile read patterns from file, base is same as Add
+func (ig *Ignore) AddFile(base, fname string) error {
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		ig.Add(base, string(line))
+	}
+
+	return nil
+}
+
+// Match report whether slash separated path relative to the root is ignored
+func (ig *Ignore) Match(rel string, isDir bool) bool {
+	if ig == nil {
+		return false
+	}
+
+	return matchIgnore(ig.patterns, strings.Trim(rel, "/"), isDir)
+}
+
+func (ig *Ignore) clone() *Ignore {
+	if ig == nil {
+		return &Ignore{}
+	}
+
+	return &Ignore{
+		patterns: ig.patterns[:len(ig.patterns):len(ig.patterns)],
+	}
+}
+
+func parseIgnorePattern(base, line string) (p ignorePattern, ok bool) {
+	line = strings.TrimSuffix(line, "\r")
+	if line == "" || line[0] == '#' {
+		return p, false
+	}
+
+	// trailing spaces are ignored unless they are escaped with backslash
+	end := len(line)
+	for end > 0 && line[end-1] == ' ' && (end < 2 || line[end-2] != '\\') {
+		end--
+	}
+	line = line[:end]
+	if strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-2] + " "
+	}
+
+	switch {
+	case line == "":
+		return p, false
+	case line[0] == '!':
+		p.negate = true
+		line = line[1:]
+	case line[0] == '\\' && len(line) > 1 && (line[1] == '#' || line[1] == '!'):
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return p, false
+	}
+
+	p.anywhere = !strings.Contains(line, "/")
+	p.base = base
+	p.segs = splitSlash(line)
+	if n := len(p.segs); n > 1 && p.segs[n-1] == "**" {
+		// "dir/**" matches everything inside dir, but not dir itself
+		p.segs = append(p.segs[:n-1], "*", "**")
+	}
+
+	return p, true
+}
+
+func matchIgnore(patterns []ignorePattern, rel string, isDir bool) bool {
+	for i := len(patterns) - 1; i >= 0; i-- {
+		p := &patterns[i]
+		if p.dirOnly && !isDir {
+			continue
+		}
+
+		name := rel
+		if p.base != "" {
+			if !strings.HasPrefix(rel, p.base+"/") {
+				continue
+			}
+			name = rel[len(p.base)+1:]
+		}
+
+		names := splitSlash(name)
+		if p.anywhere {
+			if len(names) == 0 || !matchSegments(p.segs, names[len(names)-1:]) {
+				continue
+			}
+		} else if !matchSegments(p.segs, names) {
+			continue
+		}
+
+		return !p.negate
+	}
+
+	return false
+}
@@ -0,0 +1,255 @@
+package path2
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/cosiner/gohper/errors"
+)
+
+// ErrStopWalk can be returned by walk function to stop walking without error
+const ErrStopWalk = errors.Err("stop walk")
+
+// readDir is replaced in tests to record directory reads
+var readDir = ioutil.ReadDir
+
+type WalkEntry struct {
+	Path string // path joined with root
+	Rel  string // slash separated path relative to root
+	Info os.FileInfo
+}
+
+// Walker walk directory tree concurrently, directories are read in parallel,
+// but entries are always reported in lexical depth-first order
+type Walker struct {
+	// IgnoreFiles are names of pattern files in .gitignore syntax, they are
+	// read from each directory and apply to it's sub-tree, e.g. ".gitignore"
+	IgnoreFiles []string
+	// Ignore is the patterns apply to whole tree
+	Ignore *Ignore
+	// Match patterns in doublestar syntax, relative to root, if not empty,
+	// only files match any of them are reported
+	Match []string
+	// Dirs report directories also, return filepath.SkipDir to skip it, a
+	// directory is read only after it's reported, so there is no read-ahead
+	Dirs bool
+	// Parallel is the max number of directories read concurrently and read
+	// ahead of each visiting directory, default is runtime.NumCPU()
+	Parallel int
+}
+
+// Walk walk file tree with default walker
+func Walk(root string, fn func(WalkEntry) error) error {
+	return Walker{}.Walk(root, fn)
+}
+
+// Walk call fn for each entry of the tree root, symbolic links are not
+// followed. If fn return ErrStopWalk, Walk stop and return nil, other errors
+// are returned as is.
+func (w Walker) Walk(root string, fn func(WalkEntry) error) error {
+	parallel := w.Parallel
+	if parallel <= 0 {
+		parallel = runtime.NumCPU()
+	}
+
+	wk := &walker{
+		Walker:   w,
+		parallel: parallel,
+		queue:    make(chan *dirNode, parallel),
+		stop:     make(chan struct{}),
+	}
+	wk.wg.Add(parallel)
+	for i := 0; i < parallel; i++ {
+		go wk.work()
+	}
+
+	n := newDirNode(root, "", w.Ignore.clone())
+	wk.schedule(n)
+	err := wk.visit(n, fn)
+
+	close(wk.stop)
+	wk.wg.Wait()
+
+	if err == ErrStopWalk {
+		err = nil
+	}
+
+	return err
+}
+
+type walkItem struct {
+	WalkEntry
+	dir *dirNode
+}
+
+type dirNode struct {
+	path, rel string
+	ignore    *Ignore
+	scheduled bool // only accessed by visit
+
+	done  chan struct{}
+	items []walkItem
+	err   error
+}
+
+func newDirNode(path, rel string, ignore *Ignore) *dirNode {
+	return &dirNode{
+		path:   path,
+		rel:    rel,
+		ignore: ignore,
+		done:   make(chan struct{}),
+	}
+}
+
+type walker struct {
+	Walker
+
+	parallel int
+	queue    chan *dirNode
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+func (w *walker) work() {
+	defer w.wg.Done()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case n := <-w.queue:
+			w.read(n)
+		}
+	}
+}
+
+// schedule queue the directory to be read by workers if it's not queued
+func (w *walker) schedule(n *dirNode) {
+	if !n.scheduled {
+		n.scheduled = true
+		w.queue <- n
+	}
+}
+
+func (w *walker) read(n *dirNode) {
+	defer close(n.done)
+
+	select {
+	case <-w.stop:
+		return
+	default:
+	}
+
+	ignore := n.ignore
+	for _, name := range w.IgnoreFiles {
+		fname := filepath.Join(n.path, name)
+		if _, err := os.Stat(fname); err == nil {
+			if ignore == n.ignore {
+				ignore = ignore.clone()
+			}
+			if n.err = ignore.AddFile(n.rel, fname); n.err != nil {
+				return
+			}
+		}
+	}
+
+	infos, err := readDir(n.path)
+	if err != nil {
+		n.err = err
+		return
+	}
+
+	n.items = make([]walkItem, 0, len(infos))
+	for _, info := range infos {
+		rel := path.Join(n.rel, info.Name())
+		isDir := info.IsDir()
+		if ignore.Match(rel, isDir) {
+			continue
+		}
+
+		item := walkItem{
+			WalkEntry: WalkEntry{
+				Path: filepath.Join(n.path, info.Name()),
+				Rel:  rel,
+				Info: info,
+			},
+		}
+		if isDir {
+			item.dir = newDirNode(item.Path, rel, ignore)
+		}
+		n.items = append(n.items, item)
+	}
+}
+
+func (w *walker) matched(rel string) bool {
+	if len(w.Match) == 0 {
+		return true
+	}
+
+	for _, p := range w.Match {
+		if Match(p, rel) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w *walker) visit(n *dirNode, fn func(WalkEntry) error) error {
+	<-n.done
+	if n.err != nil {
+		return n.err
+	}
+
+	// items are released once visited, next is the index of next item to
+	// read ahead, ahead is the number of sub-directories read ahead
+	items := n.items
+	n.items = nil
+	next, ahead := 0, 0
+	for i := range items {
+		item := items[i]
+		items[i] = walkItem{}
+		if item.dir == nil {
+			if w.matched(item.Rel) {
+				if err := fn(item.WalkEntry); err != nil {
+					return err
+				}
+			}
+
+			continue
+		}
+
+		if w.Dirs {
+			err := fn(item.WalkEntry)
+			if err == filepath.SkipDir {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+		} else {
+			if item.dir.scheduled {
+				ahead--
+			}
+			if next <= i {
+				next = i + 1
+			}
+			for ; next < len(items) && ahead < w.parallel; next++ {
+				if d := items[next].dir; d != nil {
+					w.schedule(d)
+					ahead++
+				}
+			}
+		}
+		w.schedule(item.dir)
+		if err := w.visit(item.dir, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
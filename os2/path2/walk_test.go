@@ -0,0 +1,154 @@
+package path2
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/cosiner/gohper/testing2"
+)
+
+func TestMatch(t *testing.T) {
+	testing2.
+		Expect(true).Arg("**/*.go", "a.go").
+		Expect(true).Arg("**/*.go", "a/b/c.go").
+		Expect(true).Arg("a/**/c.go", "a/c.go").
+		Expect(true).Arg("a/**/c.go", "a/b/d/c.go").
+		Expect(false).Arg("a/**/c.go", "b/c.go").
+		Expect(false).Arg("*.go", "a/b.go").
+		Expect(true).Arg("a/**", "a/b/c").
+		Run(t, Match)
+}
+
+func TestIgnore(t *testing.T) {
+	tt := testing2.Wrap(t)
+
+	ig := NewIgnore(
+		"# comment",
+		"*.log",
+		"!keep.log",
+		"build/",
+		"/root.txt",
+		"doc/**",
+		`\#hash`,
+	)
+	tt.True(ig.Match("a.log", false))
+	tt.True(ig.Match("x/y/a.log", false))
+	tt.False(ig.Match("x/keep.log", false))
+	tt.True(ig.Match("x/build", true))
+	tt.False(ig.Match("x/build", false))
+	tt.True(ig.Match("root.txt", false))
+	tt.False(ig.Match("x/root.txt", false))
+	tt.False(ig.Match("doc", true))
+	tt.True(ig.Match("doc/a/b.md", false))
+	tt.True(ig.Match("#hash", false))
+
+	ig.Add("sub", "*.txt")
+	tt.True(ig.Match("sub/a/b.txt", false))
+	tt.False(ig.Match("a.txt", false))
+}
+
+func TestWalk(t *testing.T) {
+	tt := testing2.Wrap(t)
+
+	dir, err := ioutil.TempDir("", "walk")
+	tt.Nil(err)
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		".gitignore":      "*.tmp\nout/\n",
+		"a.go":            "",
+		"b.tmp":           "",
+		"out/c.go":        "",
+		"pkg/.gitignore":  "!x.tmp\ngen_*.go\n",
+		"pkg/x.tmp":       "",
+		"pkg/gen_a.go":    "",
+		"pkg/d.go":        "",
+		"pkg/sub/e.go":    "",
+		"pkg/sub/f.txt":   "",
+		"vendor/v/v.go":   "",
+		"z/zz/zzz/z.go":   "",
+		"z/zz/zzz/z.txt":  "",
+		"z/zz/zzz/z2.txt": "",
+	}
+	for name, content := range files {
+		fname := filepath.Join(dir, filepath.FromSlash(name))
+		tt.Nil(os.MkdirAll(filepath.Dir(fname), 0755))
+		tt.Nil(ioutil.WriteFile(fname, []byte(content), 0644))
+	}
+
+	var rels []string
+	w := Walker{
+		IgnoreFiles: []string{".gitignore"},
+		Ignore:      NewIgnore("vendor/"),
+		Match:       []string{"**/*.go", "**/*.tmp"},
+		Parallel:    3,
+	}
+	tt.Nil(w.Walk(dir, func(e WalkEntry) error {
+		rels = append(rels, e.Rel)
+		return nil
+	}))
+	tt.DeepEq([]string{"a.go", "pkg/d.go", "pkg/sub/e.go", "pkg/x.tmp", "z/zz/zzz/z.go"}, rels)
+
+	rels = nil
+	w = Walker{Dirs: true}
+	tt.Nil(w.Walk(dir, func(e WalkEntry) error {
+		rels = append(rels, e.Rel)
+		if e.Rel == "pkg" || e.Rel == "out" || e.Rel == "vendor" {
+			return filepath.SkipDir
+		}
+		if e.Rel == "z/zz/zzz/z.txt" {
+			return ErrStopWalk
+		}
+		return nil
+	}))
+	tt.DeepEq([]string{".gitignore", "a.go", "b.tmp", "out", "pkg", "vendor", "z", "z/zz", "z/zz/zzz", "z/zz/zzz/z.go", "z/zz/zzz/z.txt"}, rels)
+}
+
+func TestWalkSkipDir(t *testing.T) {
+	tt := testing2.Wrap(t)
+
+	dir, err := ioutil.TempDir("", "walk")
+	tt.Nil(err)
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"a/.gitignore", "a/x/y.go", "b/c/.gitignore", "b/c/z/z.go", "b/d/d.go", "e/f/f.go"} {
+		fname := filepath.Join(dir, filepath.FromSlash(name))
+		tt.Nil(os.MkdirAll(filepath.Dir(fname), 0755))
+		tt.Nil(ioutil.WriteFile(fname, nil, 0644))
+	}
+
+	var (
+		lock  sync.Mutex
+		reads = make(map[string]bool)
+	)
+	readDir = func(dirname string) ([]os.FileInfo, error) {
+		lock.Lock()
+		rel, _ := filepath.Rel(dir, dirname)
+		reads[filepath.ToSlash(rel)] = true
+		lock.Unlock()
+		return ioutil.ReadDir(dirname)
+	}
+	defer func() { readDir = ioutil.ReadDir }()
+
+	var rels []string
+	w := Walker{IgnoreFiles: []string{".gitignore"}, Dirs: true, Parallel: 8}
+	tt.Nil(w.Walk(dir, func(e WalkEntry) error {
+		rels = append(rels, e.Rel)
+		if e.Rel == "a" || e.Rel == "b/c" {
+			return filepath.SkipDir
+		}
+		return nil
+	}))
+	tt.DeepEq([]string{"a", "b", "b/c", "b/d", "b/d/d.go", "e", "e/f", "e/f/f.go"}, rels)
+	tt.DeepEq(map[string]bool{".": true, "b": true, "b/d": true, "e": true, "e/f": true}, reads)
+
+	reads = make(map[string]bool)
+	w.Dirs = false
+	var n int
+	tt.Nil(w.Walk(dir, func(WalkEntry) error { n++; return nil }))
+	tt.Eq(6, n)
+	tt.Eq(9, len(reads))
+}
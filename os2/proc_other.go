@@ -0,0 +1,14 @@
+//go:build !unix
+
+package os2
+
+import (
+	"os"
+	"os/exec"
+)
+
+func setProcessGroup(*exec.Cmd) {}
+
+func killProcessGroup(p *os.Process) error {
+	return p.Kill()
+}
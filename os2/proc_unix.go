@@ -0,0 +1,19 @@
+//go:build unix
+
+package os2
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup make the process leader of a new process group, so that
+// all it's children can be killed together
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+func killProcessGroup(p *os.Process) error {
+	return syscall.Kill(-p.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,87 @@
+package runtime2
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// MaxStackDepth is the max number of frames captured by Callers
+var MaxStackDepth = 32
+
+// CallStack is program counters of a call stack, it's cheap to capture and
+// only symbolized when Frames is called
+type CallStack []uintptr
+
+type Frame struct {
+	Func string
+	File string
+	Line int
+}
+
+// Pos return frame position with dir/file:line format, same as Caller
+func (f Frame) Pos() string {
+	file := f.File
+	i := strings.LastIndexFunc(file, pathSepFunc)
+	if i >= 0 {
+		j := strings.LastIndexFunc(file[:i], pathSepFunc)
+		if j >= 0 {
+			i = j
+		}
+		file = file[i+1:]
+	}
+
+	return file + ":" + strconv.Itoa(f.Line)
+}
+
+// Callers capture call stack of current goroutine, skip means which caller
+// start from, 0 means yourself, 1 means your caller
+func Callers(skip int) CallStack {
+	pcs := make([]uintptr, MaxStackDepth)
+	n := runtime.Callers(skip+2, pcs)
+
+	return CallStack(pcs[:n])
+}
+
+func (s CallStack) Frames() []Frame {
+	if len(s) == 0 {
+		return nil
+	}
+
+	frames := make([]Frame, 0, len(s))
+	fs := runtime.CallersFrames([]uintptr(s))
+	for {
+		f, more := fs.Next()
+		frames = append(frames, Frame{
+			Func: f.Function,
+			File: f.File,
+			Line: f.Line,
+		})
+		if !more {
+			break
+		}
+	}
+
+	return frames
+}
+
+// String format call stack like panic output:
+//
+//	package.function
+//		/path/to/file.go:line
+func (s CallStack) String() string {
+	var buf bytes.Buffer
+	for i, f := range s.Frames() {
+		if i != 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(f.Func)
+		buf.WriteString("\n\t")
+		buf.WriteString(f.File)
+		buf.WriteByte(':')
+		buf.WriteString(strconv.Itoa(f.Line))
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,214 @@
+package runtime2
+
+import (
+	"bytes"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Goroutine is a goroutine record parsed from stack dump
+type Goroutine struct {
+	ID int64
+	// State is the wait reason or status, such as "running", "chan receive"
+	State string
+	// Wait is the blocked duration, it's in minutes granularity and only
+	// reported by runtime for goroutines blocked longer than one minute
+	Wait   time.Duration
+	Locked bool // locked to thread
+	Frames []Frame
+	// CreatedBy is the go statement position, Func is empty for main goroutine
+	CreatedBy Frame
+	// Creator is the id of creator goroutine, 0 if unknown
+	Creator int64
+}
+
+// Goroutines capture stack of all goroutines
+func Goroutines() []Goroutine {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return ParseStack(buf[:n])
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// ParseStack parse goroutine dump in the format of runtime.Stack and panic
+// output, unrecognized lines are skipped
+func ParseStack(data []byte) []Goroutine {
+	var (
+		gs  []Goroutine
+		g   *Goroutine
+		fn  string
+		dst *Frame // frame waiting for file line
+	)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		switch {
+		case strings.HasPrefix(line, "goroutine "):
+			gs = append(gs, Goroutine{})
+			g = &gs[len(gs)-1]
+			if !parseGoroutineHeader(line, g) {
+				gs = gs[:len(gs)-1]
+				g = nil
+			}
+			dst = nil
+		case g == nil || line == "":
+			dst = nil
+		case strings.HasPrefix(line, "\t"):
+			if dst != nil {
+				dst.File, dst.Line = parseFileLine(line)
+				dst = nil
+			}
+		case strings.HasPrefix(line, "created by "):
+			fn = line[len("created by "):]
+			if i := strings.LastIndex(fn, " in goroutine "); i >= 0 {
+				g.Creator, _ = strconv.ParseInt(fn[i+len(" in goroutine "):], 10, 64)
+				fn = fn[:i]
+			}
+			g.CreatedBy = Frame{Func: fn}
+			dst = &g.CreatedBy
+		case strings.HasPrefix(line, "..."):
+			// "...additional frames elided..."
+		default:
+			g.Frames = append(g.Frames, Frame{Func: trimArgs(line)})
+			dst = &g.Frames[len(g.Frames)-1]
+		}
+	}
+
+	return gs
+}
+
+// parseGoroutineHeader parse "goroutine 7 [chan receive, 5 minutes, locked to thread]:"
+func parseGoroutineHeader(line string, g *Goroutine) bool {
+	line = line[len("goroutine "):]
+	i := strings.IndexByte(line, ' ')
+	begin, end := strings.IndexByte(line, '['), strings.LastIndexByte(line, ']')
+	if i < 0 || begin < 0 || end < begin {
+		return false
+	}
+
+	id, err := strconv.ParseInt(line[:i], 10, 64)
+	if err != nil {
+		return false
+	}
+	g.ID = id
+
+	for i, part := range strings.Split(line[begin+1:end], ", ") {
+		switch {
+		case i == 0:
+			g.State = part
+		case part == "locked to thread":
+			g.Locked = true
+		case strings.HasSuffix(part, " minutes"):
+			if n, err := strconv.Atoi(strings.TrimSuffix(part, " minutes")); err == nil {
+				g.Wait = time.Duration(n) * time.Minute
+			}
+		}
+	}
+	return true
+}
+
+// trimArgs remove arguments of "pkg.(*T).Method(0x1, 0x2)"
+func trimArgs(fn string) string {
+	if strings.HasSuffix(fn, ")") {
+		if i := strings.LastIndexByte(fn, '('); i > 0 {
+			return fn[:i]
+		}
+	}
+	return fn
+}
+
+// parseFileLine parse "\t/path/to/file.go:12 +0x45"
+func parseFileLine(line string) (string, int) {
+	line = strings.TrimSpace(line)
+	if i := strings.LastIndex(line, " +0x"); i >= 0 {
+		line = line[:i]
+	}
+	i := strings.LastIndexByte(line, ':')
+	if i < 0 {
+		return line, 0
+	}
+	n, err := strconv.Atoi(line[i+1:])
+	if err != nil {
+		return line, 0
+	}
+	return line[:i], n
+}
+
+// StackGroup is goroutines with identical stack
+type StackGroup struct {
+	State      string
+	Frames     []Frame
+	CreatedBy  Frame
+	Goroutines []int64
+}
+
+func (s *StackGroup) Count() int {
+	return len(s.Goroutines)
+}
+
+// String format group like panic output with a count header
+func (s *StackGroup) String() string {
+	var buf bytes.Buffer
+	buf.WriteString(strconv.Itoa(s.Count()))
+	buf.WriteString(" goroutine(s) [")
+	buf.WriteString(s.State)
+	buf.WriteString("]:\n")
+	for _, f := range s.Frames {
+		writeFrame(&buf, "", f)
+	}
+	if s.CreatedBy.Func != "" {
+		writeFrame(&buf, "created by ", s.CreatedBy)
+	}
+	return buf.String()
+}
+
+func writeFrame(buf *bytes.Buffer, prefix string, f Frame) {
+	buf.WriteString(prefix)
+	buf.WriteString(f.Func)
+	buf.WriteString("\n\t")
+	buf.WriteString(f.File)
+	buf.WriteByte(':')
+	buf.WriteString(strconv.Itoa(f.Line))
+	buf.WriteByte('\n')
+}
+
+// GroupStacks group goroutines by state, frames and creator position, groups
+// are sorted by count descending
+func GroupStacks(gs []Goroutine) []*StackGroup {
+	var (
+		groups []*StackGroup
+		index  = make(map[string]*StackGroup)
+		key    bytes.Buffer
+	)
+	for _, g := range gs {
+		key.Reset()
+		key.WriteString(g.State)
+		for _, f := range g.Frames {
+			writeFrame(&key, "", f)
+		}
+		writeFrame(&key, "", g.CreatedBy)
+
+		sg := index[key.String()]
+		if sg == nil {
+			sg = &StackGroup{
+				State:     g.State,
+				Frames:    g.Frames,
+				CreatedBy: g.CreatedBy,
+			}
+			index[key.String()] = sg
+			groups = append(groups, sg)
+		}
+		sg.Goroutines = append(sg.Goroutines, g.ID)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Count() > groups[j].Count()
+	})
+	return groups
+}
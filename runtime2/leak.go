@@ -0,0 +1,68 @@
+package runtime2
+
+import (
+	"strings"
+	"time"
+)
+
+// LeakTimeout is the max time CheckLeak wait for goroutines exit
+var LeakTimeout = time.Second
+
+// TB is the subset of testing.TB used by CheckLeak
+type TB interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// CheckLeak snapshot running goroutines and return a function to check
+// goroutines created after that are all exited, otherwise report them to t.
+// Goroutines with any frame function has prefix in ignores are ignored.
+//
+//	defer runtime2.CheckLeak(t)()
+//
+// It can't distinguish goroutines of parallel tests, don't use it with them.
+func CheckLeak(t TB, ignores ...string) func() {
+	before := make(map[int64]bool)
+	for _, g := range Goroutines() {
+		before[g.ID] = true
+	}
+
+	return func() {
+		t.Helper()
+
+		var leaked []Goroutine
+		deadline := time.Now().Add(LeakTimeout)
+		for {
+			leaked = leaked[:0]
+			for _, g := range Goroutines() {
+				if !before[g.ID] && !ignoreGoroutine(g, ignores) {
+					leaked = append(leaked, g)
+				}
+			}
+			if len(leaked) == 0 || time.Now().After(deadline) {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		if len(leaked) != 0 {
+			var buf strings.Builder
+			for _, sg := range GroupStacks(leaked) {
+				buf.WriteString("\n")
+				buf.WriteString(sg.String())
+			}
+			t.Errorf("%d goroutine(s) leaked:%s", len(leaked), buf.String())
+		}
+	}
+}
+
+func ignoreGoroutine(g Goroutine, ignores []string) bool {
+	for _, f := range g.Frames {
+		for _, prefix := range ignores {
+			if strings.HasPrefix(f.Func, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package runtime2
+
+import "fmt"
+
+// PanicError is the error converted from a recovered panic
+type PanicError struct {
+	Value interface{}
+	// Stack is the call stack where panic happened
+	Stack CallStack
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap return the panic value if it's an error
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// Call run fn and convert panic to *PanicError
+func Call(fn func() error) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			// skip this function and runtime.gopanic
+			err = &PanicError{Value: e, Stack: Callers(2)}
+		}
+	}()
+
+	return fn()
+}
+
+// Go run fn in a new goroutine, if it panics, the panic is converted to
+// *PanicError and passed to handler, a nil handler ignore the panic
+func Go(fn func(), handler func(error)) {
+	go func() {
+		err := Call(func() error {
+			fn()
+			return nil
+		})
+		if err != nil && handler != nil {
+			handler(err)
+		}
+	}()
+}
@@ -1,10 +1,8 @@
 package runtime2
 
 import (
-	"fmt"
 	"os"
 	"runtime"
-	"strings"
 )
 
 type Pos struct {
@@ -21,15 +19,7 @@ func pathSepFunc(r rune) bool {
 // depth means which caller, 0 means yourself, 1 means your caller
 func Caller(depth int) string {
 	_, file, line, _ := runtime.Caller(depth + 1)
-	i := strings.LastIndexFunc(file, pathSepFunc)
-	if i >= 0 {
-		j := strings.LastIndexFunc(file[:i], pathSepFunc)
-		if j >= 0 {
-			i = j
-		}
-		file = file[i+1:]
-	}
-	return fmt.Sprintf("%s:%d", file, line)
+	return Frame{File: file, Line: line}.Pos()
 }
 
 func Stack(bufsize int, all bool) []byte {
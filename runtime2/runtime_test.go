@@ -1,14 +1,20 @@
 package runtime2
 
 import (
+	"context"
+	"errors"
+	"io"
 	"log"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/cosiner/gohper/strings2"
 )
 
 func TestCaller(t *testing.T) {
-	exp := "runtime2/runtime_test.go:12"
+	exp := "runtime2/runtime_test.go:18"
 	if p := strings2.RemoveSpace(Caller(0)); p != exp {
 		t.Fatalf("Error: expect %s, but get %s", exp, p)
 	}
@@ -26,3 +32,227 @@ func TestRecover(t *testing.T) {
 		panicFn()
 	}()
 }
+
+func TestGo(t *testing.T) {
+	errCh := make(chan error, 1)
+	Go(panicFn, func(err error) {
+		errCh <- err
+	})
+
+	err := <-errCh
+	pe, is := err.(*PanicError)
+	if !is || pe.Value != "error" || err.Error() != "panic: error" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(pe.Stack.String(), "runtime2.panicFn") {
+		t.Fatalf("stack doesn't contain panic function: %s", pe.Stack)
+	}
+
+	err = Call(func() error { panic(io.EOF) })
+	if !errors.Is(err, io.EOF) {
+		t.Fatalf("expect EOF, but get %v", err)
+	}
+	if err = Call(func() error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	for i := 0; i < 200; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("condition not satisfied")
+}
+
+func TestSupervisorOneForOne(t *testing.T) {
+	var runs int32
+	s := &Supervisor{MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+	s.Add("flaky", func(ctx context.Context) error {
+		if atomic.AddInt32(&runs, 1) < 3 {
+			panic("boom")
+		}
+		<-ctx.Done()
+		return nil
+	})
+	s.Add("oneshot", func(ctx context.Context) error {
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- s.Run(ctx)
+	}()
+
+	waitFor(t, func() bool {
+		c := s.Children()
+		return c[0].State == ChildRunning && c[0].Restarts == 2 && c[1].State == ChildStopped
+	})
+	if _, is := s.Children()[0].Err.(*PanicError); !is {
+		t.Fatal("expect panic error")
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if c := s.Children(); c[0].State != ChildStopped {
+		t.Fatal("child should be stopped")
+	}
+}
+
+func TestSupervisorOneForAll(t *testing.T) {
+	var aRuns, bRuns int32
+	s := &Supervisor{
+		Strategy:    OneForAll,
+		MinBackoff:  time.Millisecond,
+		MaxRestarts: 3,
+		Period:      time.Minute,
+	}
+	s.Add("a", func(ctx context.Context) error {
+		atomic.AddInt32(&aRuns, 1)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	s.Add("b", func(ctx context.Context) error {
+		atomic.AddInt32(&bRuns, 1)
+		time.Sleep(time.Millisecond)
+		return io.EOF
+	})
+
+	var errs int32
+	s.OnError = func(name string, err error) {
+		if name != "b" || err != io.EOF {
+			t.Errorf("unexpected error: %s %v", name, err)
+		}
+		atomic.AddInt32(&errs, 1)
+	}
+	if err := s.Run(context.Background()); err != ErrTooManyRestarts {
+		t.Fatalf("expect too many restarts, but get %v", err)
+	}
+	if errs != 4 || aRuns != 4 || bRuns != 4 {
+		t.Fatalf("unexpected counts: %d %d %d", errs, aRuns, bRuns)
+	}
+	if c := s.Children(); c[0].Restarts != 3 || c[1].Restarts != 3 {
+		t.Fatalf("unexpected restarts: %+v", c)
+	}
+}
+
+func TestNestedSupervisor(t *testing.T) {
+	sub := &Supervisor{}
+	sub.Add("leaf", func(ctx context.Context) error {
+		return nil
+	})
+
+	root := &Supervisor{}
+	root.Add("sub", sub.Run)
+	if err := root.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if c := sub.Children(); c[0].State != ChildStopped {
+		t.Fatal("leaf should be stopped")
+	}
+}
+
+const dump = `goroutine 1 [running]:
+main.main()
+	/app/main.go:10 +0x25
+
+goroutine 7 [chan receive, 5 minutes, locked to thread]:
+main.(*Server).loop(0xc000010000, {0x1, 0x2})
+	/app/server.go:42 +0x65
+created by main.Start in goroutine 1
+	/app/server.go:20 +0x85
+
+goroutine 8 [chan receive, 6 minutes]:
+main.(*Server).loop(0xc000010008, {0x1, 0x2})
+	/app/server.go:42 +0x65
+created by main.Start in goroutine 1
+	/app/server.go:20 +0x85
+`
+
+func TestParseStack(t *testing.T) {
+	gs := ParseStack([]byte(dump))
+	if len(gs) != 3 {
+		t.Fatalf("expect 3 goroutines, but get %d", len(gs))
+	}
+
+	g := gs[1]
+	if g.ID != 7 || g.State != "chan receive" || g.Wait != 5*time.Minute || !g.Locked || g.Creator != 1 {
+		t.Fatalf("unexpected goroutine: %+v", g)
+	}
+	if len(g.Frames) != 1 || g.Frames[0] != (Frame{Func: "main.(*Server).loop", File: "/app/server.go", Line: 42}) {
+		t.Fatalf("unexpected frames: %+v", g.Frames)
+	}
+	if g.CreatedBy != (Frame{Func: "main.Start", File: "/app/server.go", Line: 20}) {
+		t.Fatalf("unexpected creator: %+v", g.CreatedBy)
+	}
+	if gs[0].CreatedBy.Func != "" || gs[0].State != "running" {
+		t.Fatalf("unexpected main goroutine: %+v", gs[0])
+	}
+
+	groups := GroupStacks(gs)
+	if len(groups) != 2 || groups[0].Count() != 2 || groups[0].Goroutines[1] != 8 {
+		t.Fatalf("unexpected groups: %+v", groups)
+	}
+	if !strings.HasPrefix(groups[0].String(), "2 goroutine(s) [chan receive]:\nmain.(*Server).loop\n\t/app/server.go:42\n") {
+		t.Fatalf("unexpected group format: %s", groups[0])
+	}
+
+	found := false
+	for _, g := range Goroutines() {
+		if len(g.Frames) != 0 && strings.HasSuffix(g.Frames[0].Func, "runtime2.Goroutines") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("current goroutine not found")
+	}
+}
+
+type leakTB struct {
+	errs []string
+}
+
+func (t *leakTB) Helper() {}
+
+func (t *leakTB) Errorf(format string, args ...interface{}) {
+	t.errs = append(t.errs, format)
+}
+
+func TestCheckLeak(t *testing.T) {
+	defer CheckLeak(t)()
+
+	tb := &leakTB{}
+	stop := make(chan struct{})
+	check := CheckLeak(tb)
+	go func() {
+		<-stop
+	}()
+
+	LeakTimeout = 20 * time.Millisecond
+	defer func() {
+		LeakTimeout = time.Second
+	}()
+	check()
+	if len(tb.errs) != 1 {
+		t.Fatal("leak should be reported")
+	}
+
+	ignored := CheckLeak(tb, "github.com/cosiner/gohper/runtime2.TestCheckLeak")
+	go func() {
+		<-stop
+	}()
+	tb.errs = nil
+	ignored()
+	if len(tb.errs) != 0 {
+		t.Fatal("ignored goroutines shouldn't be reported")
+	}
+	close(stop)
+	check()
+	if len(tb.errs) != 0 {
+		t.Fatal("leak shouldn't be reported")
+	}
+}
@@ -0,0 +1,337 @@
+package runtime2
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrTooManyRestarts   = errors.New("supervisor: too many restarts")
+	ErrSupervisorRunning = errors.New("supervisor: already running")
+)
+
+type Strategy int
+
+const (
+	// OneForOne restart only the failed child
+	OneForOne Strategy = iota
+	// OneForAll stop all running children and restart them when a child failed
+	OneForAll
+)
+
+type ChildState int
+
+const (
+	ChildIdle ChildState = iota
+	ChildRunning
+	// ChildRestarting means the child is waiting for backoff or siblings exit
+	ChildRestarting
+	// ChildStopped means the child returned nil or the supervisor stopped
+	ChildStopped
+)
+
+var childStates = [...]string{
+	ChildIdle:       "idle",
+	ChildRunning:    "running",
+	ChildRestarting: "restarting",
+	ChildStopped:    "stopped",
+}
+
+func (s ChildState) String() string {
+	return childStates[s]
+}
+
+// ChildInfo is a snapshot of child status
+type ChildInfo struct {
+	Name     string
+	State    ChildState
+	Restarts int
+	// Err is the last error returned by child, panics are *PanicError
+	Err     error
+	Started time.Time
+}
+
+type child struct {
+	ChildInfo
+	fn       func(context.Context) error
+	cancel   context.CancelFunc
+	failures int // consecutive failures for backoff
+}
+
+type event struct {
+	c     *child
+	err   error
+	group []*child // children to restart
+}
+
+// Supervisor run long-running children and restart them when they return error
+// or panic, a child returns nil is considered finished and won't be restarted.
+//
+// Supervisor.Run has the same signature as children, so supervisors can be
+// nested as a tree.
+type Supervisor struct {
+	Strategy Strategy
+	// MinBackoff is the delay before first restart, it's doubled for each
+	// consecutive failure up to MaxBackoff, default 100ms and 10s. The
+	// failure count is reset if child has run longer than MaxBackoff.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// MaxRestarts in Period, if exceeded, all children are stopped and Run
+	// return ErrTooManyRestarts, 0 means no limit
+	MaxRestarts int
+	Period      time.Duration
+	// OnError is called when child failed
+	OnError func(name string, err error)
+
+	mu       sync.Mutex
+	children []*child
+	running  bool
+	ctx      context.Context
+	events   chan event
+	done     chan struct{}
+	wg       sync.WaitGroup
+	waiting  int    // children being stopped for OneForAll restart
+	failed   *child // the child triggered OneForAll restart
+	restarts []time.Time
+}
+
+// Add a child, if supervisor is running, the child is started immediately
+func (s *Supervisor) Add(name string, fn func(context.Context) error) {
+	c := &child{
+		ChildInfo: ChildInfo{Name: name},
+		fn:        fn,
+	}
+
+	s.mu.Lock()
+	s.children = append(s.children, c)
+	if s.running {
+		s.start(c)
+	}
+	s.mu.Unlock()
+}
+
+// Children return snapshots of all children in adding order
+func (s *Supervisor) Children() []ChildInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	infos := make([]ChildInfo, len(s.children))
+	for i, c := range s.children {
+		infos[i] = c.ChildInfo
+	}
+	return infos
+}
+
+// Run start all children and block until ctx is done, all children finished,
+// or restart limit is exceeded
+func (s *Supervisor) Run(ctx context.Context) error {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return ErrSupervisorRunning
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s.running = true
+	s.ctx = ctx
+	s.events = make(chan event)
+	s.done = make(chan struct{})
+	s.waiting = 0
+	s.restarts = nil
+	for _, c := range s.children {
+		s.start(c)
+	}
+	finished := s.finished()
+	s.mu.Unlock()
+
+	var err error
+	for !finished {
+		select {
+		case <-ctx.Done():
+			finished = true
+		case ev := <-s.events:
+			s.mu.Lock()
+			err = s.handle(ev)
+			finished = err != nil || s.finished()
+			s.mu.Unlock()
+		}
+	}
+
+	s.stop(cancel)
+	return err
+}
+
+func (s *Supervisor) stop(cancel context.CancelFunc) {
+	s.mu.Lock()
+	s.running = false
+	cancel()
+	close(s.done)
+	s.mu.Unlock()
+
+	s.wg.Wait()
+
+	s.mu.Lock()
+	for _, c := range s.children {
+		c.State = ChildStopped
+	}
+	s.mu.Unlock()
+}
+
+func (s *Supervisor) finished() bool {
+	for _, c := range s.children {
+		if c.State != ChildStopped {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Supervisor) start(c *child) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	c.cancel = cancel
+	c.State = ChildRunning
+	c.Started = time.Now()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer cancel()
+
+		err := Call(func() error {
+			return c.fn(ctx)
+		})
+		select {
+		case s.events <- event{c: c, err: err}:
+		case <-s.done:
+		}
+	}()
+}
+
+func (s *Supervisor) send(ev event, delay time.Duration) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-s.done:
+			return
+		}
+		select {
+		case s.events <- ev:
+		case <-s.done:
+		}
+	}()
+}
+
+func (s *Supervisor) handle(ev event) error {
+	if ev.group != nil {
+		for _, c := range ev.group {
+			c.Restarts++
+			s.start(c)
+		}
+		return nil
+	}
+
+	c := ev.c
+	if c.State == ChildRestarting {
+		// stopped by OneForAll
+		s.waiting--
+		if s.waiting == 0 {
+			s.restartAll()
+		}
+		return nil
+	}
+	if ev.err == nil {
+		c.State = ChildStopped
+		return nil
+	}
+
+	c.Err = ev.err
+	if s.OnError != nil {
+		s.OnError(c.Name, ev.err)
+	}
+	if !s.allowRestart() {
+		return ErrTooManyRestarts
+	}
+
+	_, maxBackoff := s.backoffs()
+	if time.Since(c.Started) > maxBackoff {
+		c.failures = 0
+	}
+	c.failures++
+	c.State = ChildRestarting
+
+	if s.Strategy == OneForOne {
+		s.send(event{group: []*child{c}}, s.backoff(c.failures))
+		return nil
+	}
+
+	s.failed = c
+	for _, sibling := range s.children {
+		if sibling.State == ChildRunning {
+			sibling.State = ChildRestarting
+			sibling.cancel()
+			s.waiting++
+		}
+	}
+	if s.waiting == 0 {
+		s.restartAll()
+	}
+	return nil
+}
+
+// restartAll restart all children waiting for restart, the backoff is
+// decided by the failed child
+func (s *Supervisor) restartAll() {
+	var group []*child
+	for _, c := range s.children {
+		if c.State == ChildRestarting {
+			group = append(group, c)
+		}
+	}
+	s.send(event{group: group}, s.backoff(s.failed.failures))
+}
+
+func (s *Supervisor) allowRestart() bool {
+	if s.MaxRestarts <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	i := 0
+	for ; i < len(s.restarts) && now.Sub(s.restarts[i]) > s.Period; i++ {
+	}
+	s.restarts = append(s.restarts[i:], now)
+	return len(s.restarts) <= s.MaxRestarts
+}
+
+func (s *Supervisor) backoffs() (min, max time.Duration) {
+	min, max = s.MinBackoff, s.MaxBackoff
+	if min <= 0 {
+		min = 100 * time.Millisecond
+	}
+	if max <= 0 {
+		max = 10 * time.Second
+	}
+	if max < min {
+		max = min
+	}
+	return min, max
+}
+
+func (s *Supervisor) backoff(failures int) time.Duration {
+	min, max := s.backoffs()
+	d := min
+	for i := 1; i < failures && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
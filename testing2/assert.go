@@ -0,0 +1,251 @@
+package testing2
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ErrorIs assert errors.Is(err, target)
+func (t TB) ErrorIs(err, target error) TB {
+	errorIs(t.TB, 1, err, target)
+
+	return t
+}
+
+// ErrorContains assert err is not nil and it's message contains substr
+func (t TB) ErrorContains(err error, substr string) TB {
+	errorContains(t.TB, 1, err, substr)
+
+	return t
+}
+
+// Contains assert string contains substring, slice or array contains element,
+// or map contains key
+func (t TB) Contains(container, elem interface{}) TB {
+	contains(t.TB, 1, container, elem)
+
+	return t
+}
+
+// Len assert length of string, slice, array, map or channel
+func (t TB) Len(v interface{}, n int) TB {
+	length(t.TB, 1, v, n)
+
+	return t
+}
+
+// InDelta assert the difference between expect and got is at most delta
+func (t TB) InDelta(expect, got, delta float64) TB {
+	inDelta(t.TB, 1, expect, got, delta)
+
+	return t
+}
+
+// Eventually assert cond return true before timeout, it's polled every interval
+func (t TB) Eventually(cond func() bool, timeout, interval time.Duration) TB {
+	eventually(t.TB, 1, cond, timeout, interval)
+
+	return t
+}
+
+// JSONEq assert two json documents are semantically equal, key order and
+// spaces are ignored
+func (t TB) JSONEq(expect, got string) TB {
+	jsonEq(t.TB, 1, expect, got)
+
+	return t
+}
+
+// Panics assert fn panics, if expect is an error, the panic value must be an
+// error matches it by errors.Is, if expect is not nil, the panic value must
+// be deep-equal to it
+func (t TB) Panics(fn func(), expect interface{}) TB {
+	panics(t.TB, 1, fn, expect)
+
+	return t
+}
+
+func ErrorIs(t testing.TB, err, target error) TB {
+	errorIs(t, 1, err, target)
+
+	return Wrap(t)
+}
+
+func ErrorContains(t testing.TB, err error, substr string) TB {
+	errorContains(t, 1, err, substr)
+
+	return Wrap(t)
+}
+
+func Contains(t testing.TB, container, elem interface{}) TB {
+	contains(t, 1, container, elem)
+
+	return Wrap(t)
+}
+
+func Len(t testing.TB, v interface{}, n int) TB {
+	length(t, 1, v, n)
+
+	return Wrap(t)
+}
+
+func InDelta(t testing.TB, expect, got, delta float64) TB {
+	inDelta(t, 1, expect, got, delta)
+
+	return Wrap(t)
+}
+
+func Eventually(t testing.TB, cond func() bool, timeout, interval time.Duration) TB {
+	eventually(t, 1, cond, timeout, interval)
+
+	return Wrap(t)
+}
+
+func JSONEq(t testing.TB, expect, got string) TB {
+	jsonEq(t, 1, expect, got)
+
+	return Wrap(t)
+}
+
+func Panics(t testing.TB, fn func(), expect interface{}) TB {
+	panics(t, 1, fn, expect)
+
+	return Wrap(t)
+}
+
+func errorIs(t testing.TB, skip int, err, target error) {
+	if !errors.Is(err, target) {
+		errorInfo(t, skip+1, fmt.Sprintf("error is %v", target), fmt.Sprintf("%v", err), false)
+	}
+}
+
+func errorContains(t testing.TB, skip int, err error, substr string) {
+	if err == nil {
+		errorInfo(t, skip+1, fmt.Sprintf("error contains %q", substr), "nil", false)
+	} else if !strings.Contains(err.Error(), substr) {
+		errorInfo(t, skip+1, fmt.Sprintf("error contains %q", substr), fmt.Sprintf("%q", err.Error()), false)
+	}
+}
+
+func contains(t testing.TB, skip int, container, elem interface{}) {
+	found, ok := includes(container, elem)
+	if !ok {
+		errorInfo(t, skip+1, "string, slice, array or map", fmt.Sprintf("%T", container), false)
+	} else if !found {
+		errorInfo(t, skip+1, fmt.Sprintf("contains %#v", elem), fmt.Sprintf("%#v", container), false)
+	}
+}
+
+// includes report whether container contains elem, ok is false if container
+// isn't a string, slice, array or map
+func includes(container, elem interface{}) (found, ok bool) {
+	v := reflect.ValueOf(container)
+	switch v.Kind() {
+	case reflect.String:
+		e := reflect.ValueOf(elem)
+		if e.Kind() != reflect.String {
+			return false, true
+		}
+		return strings.Contains(v.String(), e.String()), true
+	case reflect.Slice, reflect.Array:
+		for i, n := 0, v.Len(); i < n; i++ {
+			if reflect.DeepEqual(v.Index(i).Interface(), elem) {
+				return true, true
+			}
+		}
+		return false, true
+	case reflect.Map:
+		e := reflect.ValueOf(elem)
+		if !e.IsValid() || !e.Type().AssignableTo(v.Type().Key()) {
+			return false, true
+		}
+		return v.MapIndex(e).IsValid(), true
+	default:
+		return false, false
+	}
+}
+
+func length(t testing.TB, skip int, v interface{}, n int) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map, reflect.Chan:
+		if l := rv.Len(); l != n {
+			errorInfo(t, skip+1, fmt.Sprintf("length %d", n), fmt.Sprintf("length %d: %+v", l, v), false)
+		}
+	default:
+		errorInfo(t, skip+1, "string, slice, array, map or channel", fmt.Sprintf("%T", v), false)
+	}
+}
+
+func inDelta(t testing.TB, skip int, expect, got, delta float64) {
+	if diff := math.Abs(expect - got); !(diff <= delta) {
+		errorInfo(t, skip+1, fmt.Sprintf("%v±%v", expect, delta), fmt.Sprintf("%v(delta %v)", got, diff), false)
+	}
+}
+
+func eventually(t testing.TB, skip int, cond func() bool, timeout, interval time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			errorInfo(t, skip+1, "condition satisfied in "+timeout.String(), "timeout", false)
+			return
+		}
+		time.Sleep(interval)
+	}
+}
+
+func jsonEq(t testing.TB, skip int, expect, got string) {
+	var e, g interface{}
+	if err := json.Unmarshal([]byte(expect), &e); err != nil {
+		errorInfo(t, skip+1, "valid expect json", err.Error(), false)
+		return
+	}
+	if err := json.Unmarshal([]byte(got), &g); err != nil {
+		errorInfo(t, skip+1, "valid json", err.Error(), false)
+		return
+	}
+
+	if !reflect.DeepEqual(e, g) {
+		indexErrorDetail(t, skip+1, "", expect, got, false, "\n"+Diff(e, g))
+	}
+}
+
+func panics(t testing.TB, skip int, fn func(), expect interface{}) {
+	e, panicked := catch(fn)
+	if !panicked {
+		errorInfo(t, skip+1, "panic", "not panic", false)
+		return
+	}
+	if expect == nil {
+		return
+	}
+
+	if target, is := expect.(error); is {
+		if err, is := e.(error); !is || !errors.Is(err, target) {
+			errorInfo(t, skip+1, fmt.Sprintf("panic: %v", target), fmt.Sprintf("panic: %v", e), false)
+		}
+	} else if !reflect.DeepEqual(expect, e) {
+		errorInfo(t, skip+1, fmt.Sprintf("panic: %#v", expect), fmt.Sprintf("panic: %#v", e), false)
+	}
+}
+
+// catch run fn and return the recovered value, panicked is true even if
+// the panic value is nil
+func catch(fn func()) (e interface{}, panicked bool) {
+	panicked = true
+	defer func() {
+		if panicked {
+			e = recover()
+		}
+	}()
+
+	fn()
+	panicked = false
+	return nil, false
+}
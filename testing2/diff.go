@@ -0,0 +1,190 @@
+package testing2
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// MaxDiffs is the max number of differences reported by Diff
+var MaxDiffs = 20
+
+// Diff report structural differences between expect and got, one line for
+// each difference with the path of the value, such as:
+//
+//	.Users[1].Name: expect "a", got "b"
+//	["key"]: expect <missing>, got 1
+//
+// Empty string means they are deep-equal.
+func Diff(expect, got interface{}) string {
+	d := differ{visited: make(map[visit]bool)}
+	d.diff("", reflect.ValueOf(expect), reflect.ValueOf(got))
+	if d.more > 0 {
+		d.lines = append(d.lines, fmt.Sprintf("... %d more differences", d.more))
+	}
+
+	return strings.Join(d.lines, "\n")
+}
+
+type visit struct {
+	a, b uintptr
+	typ  reflect.Type
+}
+
+type differ struct {
+	lines   []string
+	more    int
+	visited map[visit]bool
+}
+
+const missing = "<missing>"
+
+func (d *differ) report(path string, expect, got string) {
+	if len(d.lines) >= MaxDiffs {
+		d.more++
+		return
+	}
+	if path == "" {
+		path = "value"
+	}
+	d.lines = append(d.lines, path+": expect "+expect+", got "+got)
+}
+
+func formatValue(v reflect.Value) string {
+	if !v.IsValid() {
+		return "nil"
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return fmt.Sprintf("%q", v.String())
+	case reflect.Slice, reflect.Map, reflect.Ptr, reflect.Interface, reflect.Func, reflect.Chan:
+		if v.IsNil() {
+			return "nil(" + v.Type().String() + ")"
+		}
+	}
+
+	return fmt.Sprintf("%+v", v)
+}
+
+func (d *differ) diff(path string, a, b reflect.Value) {
+	if !a.IsValid() || !b.IsValid() {
+		if a.IsValid() != b.IsValid() {
+			d.report(path, formatValue(a), formatValue(b))
+		}
+		return
+	}
+	if a.Type() != b.Type() {
+		d.report(path, formatValue(a)+"("+a.Type().String()+")", formatValue(b)+"("+b.Type().String()+")")
+		return
+	}
+
+	switch a.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				d.report(path, formatValue(a), formatValue(b))
+			}
+			return
+		}
+		if a.Pointer() == b.Pointer() && (a.Kind() != reflect.Slice || a.Len() == b.Len()) {
+			return
+		}
+		v := visit{a: a.Pointer(), b: b.Pointer(), typ: a.Type()}
+		if d.visited[v] {
+			return
+		}
+		d.visited[v] = true
+	}
+
+	switch a.Kind() {
+	case reflect.Ptr:
+		d.diff(path, a.Elem(), b.Elem())
+	case reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				d.report(path, formatValue(a), formatValue(b))
+			}
+			return
+		}
+		d.diff(path, a.Elem(), b.Elem())
+	case reflect.Struct:
+		for i, n := 0, a.NumField(); i < n; i++ {
+			d.diff(path+"."+a.Type().Field(i).Name, a.Field(i), b.Field(i))
+		}
+	case reflect.Slice, reflect.Array:
+		la, lb := a.Len(), b.Len()
+		for i := 0; i < la || i < lb; i++ {
+			p := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= lb:
+				d.report(p, formatValue(a.Index(i)), missing)
+			case i >= la:
+				d.report(p, missing, formatValue(b.Index(i)))
+			default:
+				d.diff(p, a.Index(i), b.Index(i))
+			}
+		}
+	case reflect.Map:
+		for _, k := range sortedKeys(a, b) {
+			p := fmt.Sprintf("%s[%s]", path, formatValue(k))
+			va, vb := a.MapIndex(k), b.MapIndex(k)
+			switch {
+			case !vb.IsValid():
+				d.report(p, formatValue(va), missing)
+			case !va.IsValid():
+				d.report(p, missing, formatValue(vb))
+			default:
+				d.diff(p, va, vb)
+			}
+		}
+	case reflect.Func:
+		if !a.IsNil() || !b.IsNil() {
+			d.report(path, "func", "func(functions are only equal if both nil)")
+		}
+	case reflect.Chan, reflect.UnsafePointer:
+		if a.Pointer() != b.Pointer() {
+			d.report(path, formatValue(a), formatValue(b))
+		}
+	case reflect.Bool:
+		if a.Bool() != b.Bool() {
+			d.report(path, formatValue(a), formatValue(b))
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if a.Int() != b.Int() {
+			d.report(path, formatValue(a), formatValue(b))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if a.Uint() != b.Uint() {
+			d.report(path, formatValue(a), formatValue(b))
+		}
+	case reflect.Float32, reflect.Float64:
+		if a.Float() != b.Float() {
+			d.report(path, formatValue(a), formatValue(b))
+		}
+	case reflect.Complex64, reflect.Complex128:
+		if a.Complex() != b.Complex() {
+			d.report(path, formatValue(a), formatValue(b))
+		}
+	case reflect.String:
+		if a.String() != b.String() {
+			d.report(path, formatValue(a), formatValue(b))
+		}
+	}
+}
+
+// sortedKeys return union of keys of two maps sorted by their formats
+func sortedKeys(a, b reflect.Value) []reflect.Value {
+	var keys []reflect.Value
+	keys = append(keys, a.MapKeys()...)
+	for _, k := range b.MapKeys() {
+		if !a.MapIndex(k).IsValid() {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return formatValue(keys[i]) < formatValue(keys[j])
+	})
+	return keys
+}
@@ -0,0 +1,288 @@
+package testing2
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cosiner/gohper/runtime2"
+)
+
+// GoldenDir is the directory of golden files, relative to package directory
+var GoldenDir = "testdata"
+
+func init() {
+	if flag.Lookup("update") == nil {
+		flag.Bool("update", false, "rewrite golden files with actual output")
+	}
+}
+
+// updateGolden report whether the -update flag is set, it may be defined by
+// other package
+func updateGolden() bool {
+	f := flag.Lookup("update")
+	if f == nil {
+		return false
+	}
+	if g, is := f.Value.(flag.Getter); is {
+		update, _ := g.Get().(bool)
+		return update
+	}
+	return f.Value.String() == "true"
+}
+
+// GoldenFile return path of golden file: GoldenDir/name.golden
+func GoldenFile(name string) string {
+	return filepath.Join(GoldenDir, name+".golden")
+}
+
+// Golden compare got with golden file of the name, line endings are
+// normalized to "\n", a unified diff is reported on mismatch. With -update
+// flag, the golden file is rewritten. The flag is registered by this package
+// if no package initialized before defined it.
+func (t TB) Golden(name string, got []byte) TB {
+	golden(t.TB, 1, name, got, false)
+
+	return t
+}
+
+// GoldenJSON is same as Golden, but compare json semantically, the golden
+// file is written with indent and sorted keys
+func (t TB) GoldenJSON(name string, got []byte) TB {
+	golden(t.TB, 1, name, got, true)
+
+	return t
+}
+
+func Golden(t testing.TB, name string, got []byte) TB {
+	golden(t, 1, name, got, false)
+
+	return Wrap(t)
+}
+
+func GoldenJSON(t testing.TB, name string, got []byte) TB {
+	golden(t, 1, name, got, true)
+
+	return Wrap(t)
+}
+
+func golden(t testing.TB, skip int, name string, got []byte, isJSON bool) {
+	pos := runtime2.Caller(skip + 1)
+	fname := GoldenFile(name)
+
+	var gotVal interface{}
+	if isJSON {
+		if err := json.Unmarshal(got, &gotVal); err != nil {
+			t.Errorf("%s: invalid json: %s", pos, err)
+			return
+		}
+		got = canonicalJSON(gotVal)
+	} else {
+		got = normalizeLines(got)
+	}
+
+	if updateGolden() {
+		err := os.MkdirAll(filepath.Dir(fname), 0755)
+		if err == nil {
+			err = ioutil.WriteFile(fname, got, 0644)
+		}
+		if err != nil {
+			t.Errorf("%s: update golden file: %s", pos, err)
+		}
+		return
+	}
+
+	expect, err := ioutil.ReadFile(fname)
+	if err != nil {
+		t.Errorf("%s: read golden file: %s, run test with -update to create it", pos, err)
+		return
+	}
+
+	if isJSON {
+		var expectVal interface{}
+		if err := json.Unmarshal(expect, &expectVal); err != nil {
+			t.Errorf("%s: invalid json in %s: %s", pos, fname, err)
+			return
+		}
+		if reflect.DeepEqual(expectVal, gotVal) {
+			return
+		}
+		expect = canonicalJSON(expectVal)
+	} else {
+		expect = normalizeLines(expect)
+		if bytes.Equal(expect, got) {
+			return
+		}
+	}
+
+	t.Errorf("%s: output doesn't match %s, run test with -update to rewrite it:\n%s",
+		pos, fname, UnifiedDiff(fname, "got", string(expect), string(got)))
+}
+
+func normalizeLines(data []byte) []byte {
+	data = bytes.Replace(data, []byte("\r\n"), []byte("\n"), -1)
+	return bytes.Replace(data, []byte("\r"), []byte("\n"), -1)
+}
+
+// canonicalJSON format decoded json with indent, map keys are sorted by
+// encoding/json
+func canonicalJSON(v interface{}) []byte {
+	data, _ := json.MarshalIndent(v, "", "  ")
+	return append(data, '\n')
+}
+
+// DiffContext is the number of context lines of UnifiedDiff
+var DiffContext = 3
+
+// UnifiedDiff return the unified diff of two texts, empty string if equal
+func UnifiedDiff(expectName, gotName, expect, got string) string {
+	if expect == got {
+		return ""
+	}
+
+	a, b := splitLines(expect), splitLines(got)
+	ops := diffLines(a, b)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", expectName, gotName)
+	for i := 0; i < len(ops); {
+		// skip equal lines not in context
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		start := i - DiffContext
+		if start < 0 {
+			start = 0
+		}
+		// extend hunk while the gap between changes is small
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			gap := end
+			for gap < len(ops) && ops[gap].kind == ' ' {
+				gap++
+			}
+			if gap == len(ops) || gap-end > 2*DiffContext {
+				end += DiffContext
+				if end > len(ops) {
+					end = len(ops)
+				}
+				break
+			}
+			end = gap
+		}
+
+		writeHunk(&buf, ops[start:end])
+		i = end
+	}
+
+	return buf.String()
+}
+
+type diffOp struct {
+	kind   byte // ' ', '-', '+'
+	line   string
+	ai, bi int // line numbers start from 1 in a and b before this op
+}
+
+func writeHunk(buf *bytes.Buffer, ops []diffOp) {
+	var na, nb int
+	for _, op := range ops {
+		if op.kind != '+' {
+			na++
+		}
+		if op.kind != '-' {
+			nb++
+		}
+	}
+
+	fmt.Fprintf(buf, "@@ -%s +%s @@\n", hunkRange(ops[0].ai, na), hunkRange(ops[0].bi, nb))
+	for _, op := range ops {
+		buf.WriteByte(op.kind)
+		buf.WriteString(op.line)
+		buf.WriteByte('\n')
+	}
+}
+
+func hunkRange(start, n int) string {
+	if n == 0 {
+		start--
+	}
+	if n == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, n)
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// maxDiffCells limit memory of the LCS table, large inputs are reported as
+// replacing all lines
+const maxDiffCells = 1 << 22
+
+// diffLines compute edit operations by longest common subsequence
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	var ops []diffOp
+	if n*m > maxDiffCells {
+		for i, l := range a {
+			ops = append(ops, diffOp{kind: '-', line: l, ai: i + 1, bi: 1})
+		}
+		for j, l := range b {
+			ops = append(ops, diffOp{kind: '+', line: l, ai: n + 1, bi: j + 1})
+		}
+		return ops
+	}
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int32, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && a[i] == b[j]:
+			ops = append(ops, diffOp{kind: ' ', line: a[i], ai: i + 1, bi: j + 1})
+			i++
+			j++
+		case j == m || (i < n && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{kind: '-', line: a[i], ai: i + 1, bi: j + 1})
+			i++
+		default:
+			ops = append(ops, diffOp{kind: '+', line: b[j], ai: i + 1, bi: j + 1})
+			j++
+		}
+	}
+	return ops
+}
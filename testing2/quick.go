@@ -0,0 +1,764 @@
+package testing2
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cosiner/gohper/runtime2"
+)
+
+var seedFlag = flag.Int64("testing2.seed", 0, "random seed for property tests, 0 means use Property.Seed or current time")
+
+// Gen generate random values of a type, size is a hint of value complexity,
+// it grows from 1 to Property.MaxSize as runs go on
+type Gen interface {
+	Type() reflect.Type
+	Generate(r *rand.Rand, size int) reflect.Value
+	// Shrink return candidates simpler than v, simplest first
+	Shrink(v reflect.Value) []reflect.Value
+}
+
+// Property check a function holds for random arguments
+type Property struct {
+	// Runs is the number of checks, default 100
+	Runs int
+	// Seed of random source, 0 means use current time, the seed is reported
+	// on failure, it can also be overridden by the -testing2.seed flag
+	Seed int64
+	// MaxSize is the max size passed to generators, default 100
+	MaxSize int
+	// MaxShrinks is the max number of shrink attempts, default 1000
+	MaxShrinks int
+	// Gens for each argument, if empty, they are derived from argument types
+	// by TypeGen, a nil Gen is also derived
+	Gens []Gen
+}
+
+// Check is shortcut of Property{Gens: gens}.Check
+func Check(t testing.TB, fn interface{}, gens ...Gen) {
+	Property{Gens: gens}.check(t, 1, fn)
+}
+
+// Check call fn with generated arguments, fn must return a bool or an error,
+// false, non-nil error and panic means failure. The failed arguments are
+// shrunk to a minimal counterexample and reported with the seed.
+func (p Property) Check(t testing.TB, fn interface{}) {
+	p.check(t, 1, fn)
+}
+
+func (p Property) check(t testing.TB, skip int, fn interface{}) {
+	pos := runtime2.Caller(skip + 1)
+	fv := reflect.ValueOf(fn)
+	ft := fv.Type()
+	if ft.Kind() != reflect.Func || ft.NumOut() != 1 ||
+		(ft.Out(0).Kind() != reflect.Bool && ft.Out(0) != errorType) {
+		panic("property function must return a bool or an error")
+	}
+
+	gens := make([]Gen, ft.NumIn())
+	if len(p.Gens) != 0 && len(p.Gens) != len(gens) {
+		panic("count of generators and arguments doesn't match")
+	}
+	for i := range gens {
+		if i < len(p.Gens) && p.Gens[i] != nil {
+			gens[i] = p.Gens[i]
+		} else {
+			gens[i] = TypeGen(ft.In(i))
+		}
+	}
+
+	if p.Runs <= 0 {
+		p.Runs = 100
+	}
+	if p.MaxSize <= 0 {
+		p.MaxSize = 100
+	}
+	if p.MaxShrinks <= 0 {
+		p.MaxShrinks = 1000
+	}
+	seed := p.Seed
+	if *seedFlag != 0 {
+		seed = *seedFlag
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	r := rand.New(rand.NewSource(seed))
+	args := make([]reflect.Value, len(gens))
+	for run := 0; run < p.Runs; run++ {
+		size := 1 + run*(p.MaxSize-1)/p.Runs
+		for i, g := range gens {
+			args[i] = g.Generate(r, size)
+		}
+
+		if err := callProperty(fv, args); err != nil {
+			origin := formatArgs(args)
+			shrunk, steps, err := shrinkArgs(fv, gens, args, err, p.MaxShrinks)
+			t.Errorf("%s: property failed at run %d (seed %d, rerun with -testing2.seed=%d)\n\targs: %s\n\tshrunk (%d steps): %s\n\terror: %v",
+				pos, run+1, seed, seed, origin, steps, formatArgs(shrunk), err)
+			return
+		}
+	}
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+func callProperty(fn reflect.Value, args []reflect.Value) error {
+	var out []reflect.Value
+	e, panicked := catch(func() {
+		out = fn.Call(args)
+	})
+	if panicked {
+		return fmt.Errorf("panic: %v", e)
+	}
+
+	if out[0].Kind() == reflect.Bool {
+		if !out[0].Bool() {
+			return fmt.Errorf("property returns false")
+		}
+		return nil
+	}
+	if out[0].IsNil() {
+		return nil
+	}
+	return out[0].Interface().(error)
+}
+
+// shrinkArgs greedily replace each argument with it's simpler candidates as
+// long as the property still fails
+func shrinkArgs(fn reflect.Value, gens []Gen, args []reflect.Value, err error, maxShrinks int) ([]reflect.Value, int, error) {
+	args = append([]reflect.Value(nil), args...)
+	steps, attempts := 0, 0
+	for shrunk := true; shrunk && attempts < maxShrinks; {
+		shrunk = false
+		for i := 0; i < len(args) && !shrunk; i++ {
+			orig := args[i]
+			for _, c := range gens[i].Shrink(orig) {
+				if attempts++; attempts > maxShrinks {
+					break
+				}
+
+				args[i] = c
+				if e := callProperty(fn, args); e != nil {
+					err = e
+					steps++
+					shrunk = true
+					break
+				}
+				args[i] = orig
+			}
+		}
+	}
+
+	return args, steps, err
+}
+
+func formatArgs(args []reflect.Value) string {
+	s := make([]string, len(args))
+	for i, a := range args {
+		s[i] = fmt.Sprintf("%#v", a)
+	}
+
+	return "(" + strings.Join(s, ", ") + ")"
+}
+
+// TypeGen derive generator from type, supported kinds are bool, integers,
+// floats, string, slice, array, map, struct and pointer. Unexported struct
+// fields are left zero. Numbers are in range [-size, size], lengths are in
+// range [0, size]. For recursive types, size is halved at each level of
+// recursion and split among slice and map elements, nil pointers, empty
+// slices and maps are generated after it's used up.
+func TypeGen(typ reflect.Type) Gen {
+	return typeGen(typ, make(map[reflect.Type]*recGen))
+}
+
+// typeGen derive generator, seen is struct types being derived
+func typeGen(typ reflect.Type, seen map[reflect.Type]*recGen) Gen {
+	switch typ.Kind() {
+	case reflect.Bool:
+		return boolGen{typ: typ}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return intGen{typ: typ, sized: true}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return uintGen{typ: typ, sized: true}
+	case reflect.Float32, reflect.Float64:
+		return floatGen{typ: typ, sized: true}
+	case reflect.String:
+		return stringGen{typ: typ}
+	case reflect.Slice:
+		return sliceGen{typ: typ, elem: typeGen(typ.Elem(), seen)}
+	case reflect.Array:
+		return arrayGen{typ: typ, elem: typeGen(typ.Elem(), seen)}
+	case reflect.Map:
+		return mapGen{typ: typ, key: typeGen(typ.Key(), seen), val: typeGen(typ.Elem(), seen)}
+	case reflect.Ptr:
+		return ptrGen{typ: typ, elem: typeGen(typ.Elem(), seen)}
+	case reflect.Struct:
+		return structOf(typ, nil, seen)
+	}
+
+	panic("unsupported type for generator: " + typ.String())
+}
+
+// IntRange generate int in [min, max], shrinking towards the value closest to 0
+func IntRange(min, max int) Gen {
+	return intGen{typ: reflect.TypeOf(0), min: int64(min), max: int64(max)}
+}
+
+// Float64Range generate float64 in [min, max)
+func Float64Range(min, max float64) Gen {
+	return floatGen{typ: reflect.TypeOf(0.0), min: min, max: max}
+}
+
+func Bools() Gen {
+	return boolGen{typ: reflect.TypeOf(false)}
+}
+
+// Strings generate strings with runes in alphabet, empty alphabet means
+// printable ascii characters, maxLen 0 means use size
+func Strings(alphabet string, maxLen int) Gen {
+	return stringGen{typ: reflect.TypeOf(""), alphabet: []rune(alphabet), maxLen: maxLen}
+}
+
+// SliceOf generate slices of elem, maxLen 0 means use size
+func SliceOf(elem Gen, maxLen int) Gen {
+	return sliceGen{typ: reflect.SliceOf(elem.Type()), elem: elem, maxLen: maxLen}
+}
+
+// MapOf generate maps, maxLen 0 means use size
+func MapOf(key, val Gen, maxLen int) Gen {
+	return mapGen{typ: reflect.MapOf(key.Type(), val.Type()), key: key, val: val, maxLen: maxLen}
+}
+
+// StructOf generate values of the struct type of v, fields not in gens are
+// derived by TypeGen
+func StructOf(v interface{}, gens map[string]Gen) Gen {
+	return structOf(reflect.TypeOf(v), gens, make(map[reflect.Type]*recGen))
+}
+
+func structOf(typ reflect.Type, gens map[string]Gen, seen map[reflect.Type]*recGen) Gen {
+	if rec, has := seen[typ]; has {
+		return rec
+	}
+	rec := &recGen{typ: typ}
+	seen[typ] = rec
+	defer delete(seen, typ)
+
+	g := structGen{typ: typ, fields: make([]Gen, typ.NumField())}
+	for i := range g.fields {
+		f := typ.Field(i)
+		if gen, has := gens[f.Name]; has {
+			g.fields[i] = gen
+		} else if f.PkgPath == "" {
+			g.fields[i] = typeGen(f.Type, seen)
+		}
+	}
+	rec.gen = g
+
+	return g
+}
+
+// recGen refer to a struct type contains itself, it halve the size at each
+// level so that generation stops
+type recGen struct {
+	typ reflect.Type
+	gen Gen
+}
+
+func (g *recGen) Type() reflect.Type { return g.typ }
+
+func (g *recGen) Generate(r *rand.Rand, size int) reflect.Value {
+	return g.gen.Generate(r, size/2)
+}
+
+func (g *recGen) Shrink(v reflect.Value) []reflect.Value {
+	return g.gen.Shrink(v)
+}
+
+// isRec report whether elem is a recursive struct or pointer to it
+func isRec(elem Gen) bool {
+	if p, is := elem.(ptrGen); is {
+		elem = p.elem
+	}
+	_, is := elem.(*recGen)
+	return is
+}
+
+// exhausted report whether size is used up for recursive elem
+func exhausted(elem Gen, size int) bool {
+	return size/2 <= 0 && isRec(elem)
+}
+
+// elemSize split size among n recursive elements to keep the total bounded
+func elemSize(elem Gen, size, n int) int {
+	if n > 1 && isRec(elem) {
+		return size / n
+	}
+	return size
+}
+
+type boolGen struct {
+	typ reflect.Type
+}
+
+func (g boolGen) Type() reflect.Type { return g.typ }
+
+func (g boolGen) Generate(r *rand.Rand, size int) reflect.Value {
+	v := reflect.New(g.typ).Elem()
+	v.SetBool(r.Intn(2) == 1)
+	return v
+}
+
+func (g boolGen) Shrink(v reflect.Value) []reflect.Value {
+	if v.Bool() {
+		return []reflect.Value{reflect.Zero(g.typ)}
+	}
+	return nil
+}
+
+type intGen struct {
+	typ      reflect.Type
+	min, max int64
+	sized    bool
+}
+
+func (g intGen) Type() reflect.Type { return g.typ }
+
+func (g intGen) bounds(size int) (int64, int64) {
+	if !g.sized {
+		return g.min, g.max
+	}
+
+	max := int64(size)
+	if bits := g.typ.Bits(); bits < 64 && max > 1<<uint(bits-1)-1 {
+		max = 1<<uint(bits-1) - 1
+	}
+	return -max, max
+}
+
+func (g intGen) Generate(r *rand.Rand, size int) reflect.Value {
+	min, max := g.bounds(size)
+	v := reflect.New(g.typ).Elem()
+	span := uint64(max - min)
+	if span == math.MaxUint64 {
+		v.SetInt(int64(r.Uint64()))
+	} else {
+		v.SetInt(min + int64(r.Uint64()%(span+1)))
+	}
+	return v
+}
+
+func (g intGen) Shrink(v reflect.Value) []reflect.Value {
+	n, target := v.Int(), int64(0)
+	if !g.sized {
+		if target < g.min {
+			target = g.min
+		} else if target > g.max {
+			target = g.max
+		}
+	}
+
+	var cs []reflect.Value
+	for _, c := range shrinkInt(n, target) {
+		cv := reflect.New(g.typ).Elem()
+		cv.SetInt(c)
+		cs = append(cs, cv)
+	}
+	return cs
+}
+
+// shrinkInt return target, the middle of n and target, and n's neighbor
+// towards target
+func shrinkInt(n, target int64) []int64 {
+	if n == target {
+		return nil
+	}
+
+	cs := []int64{target}
+	if mid := n - (n-target)/2; mid != n && mid != target {
+		cs = append(cs, mid)
+	}
+	next := n - 1
+	if n < target {
+		next = n + 1
+	}
+	if next != target {
+		cs = append(cs, next)
+	}
+	return cs
+}
+
+type uintGen struct {
+	typ   reflect.Type
+	sized bool
+}
+
+func (g uintGen) Type() reflect.Type { return g.typ }
+
+func (g uintGen) Generate(r *rand.Rand, size int) reflect.Value {
+	max := uint64(size)
+	if bits := g.typ.Bits(); bits < 64 && max > 1<<uint(bits)-1 {
+		max = 1<<uint(bits) - 1
+	}
+	v := reflect.New(g.typ).Elem()
+	v.SetUint(r.Uint64() % (max + 1))
+	return v
+}
+
+func (g uintGen) Shrink(v reflect.Value) []reflect.Value {
+	n := v.Uint()
+	if n > math.MaxInt64 {
+		return []reflect.Value{reflect.Zero(g.typ)}
+	}
+
+	var cs []reflect.Value
+	for _, c := range shrinkInt(int64(n), 0) {
+		cv := reflect.New(g.typ).Elem()
+		cv.SetUint(uint64(c))
+		cs = append(cs, cv)
+	}
+	return cs
+}
+
+type floatGen struct {
+	typ      reflect.Type
+	min, max float64
+	sized    bool
+}
+
+func (g floatGen) Type() reflect.Type { return g.typ }
+
+func (g floatGen) Generate(r *rand.Rand, size int) reflect.Value {
+	min, max := g.min, g.max
+	if g.sized {
+		min, max = -float64(size), float64(size)
+	}
+	v := reflect.New(g.typ).Elem()
+	v.SetFloat(min + r.Float64()*(max-min))
+	return v
+}
+
+func (g floatGen) Shrink(v reflect.Value) []reflect.Value {
+	f, target := v.Float(), 0.0
+	if !g.sized {
+		target = math.Max(g.min, math.Min(target, g.max))
+	}
+	if f == target {
+		return nil
+	}
+
+	var cs []reflect.Value
+	add := func(c float64) {
+		if c != f && (g.sized || (c >= g.min && c <= g.max)) {
+			cv := reflect.New(g.typ).Elem()
+			cv.SetFloat(c)
+			cs = append(cs, cv)
+		}
+	}
+	add(target)
+	add(math.Trunc(f))
+	add(target + (f-target)/2)
+	return cs
+}
+
+type stringGen struct {
+	typ      reflect.Type
+	alphabet []rune
+	maxLen   int
+}
+
+func (g stringGen) Type() reflect.Type { return g.typ }
+
+func (g stringGen) Generate(r *rand.Rand, size int) reflect.Value {
+	max := g.maxLen
+	if max <= 0 {
+		max = size
+	}
+
+	rs := make([]rune, r.Intn(max+1))
+	for i := range rs {
+		if len(g.alphabet) == 0 {
+			rs[i] = rune(' ' + r.Intn('~'-' '+1))
+		} else {
+			rs[i] = g.alphabet[r.Intn(len(g.alphabet))]
+		}
+	}
+	v := reflect.New(g.typ).Elem()
+	v.SetString(string(rs))
+	return v
+}
+
+func (g stringGen) Shrink(v reflect.Value) []reflect.Value {
+	rs := []rune(v.String())
+	simplest := 'a'
+	if len(g.alphabet) != 0 {
+		simplest = g.alphabet[0]
+	}
+
+	var cs []reflect.Value
+	add := func(rs []rune) {
+		cv := reflect.New(g.typ).Elem()
+		cv.SetString(string(rs))
+		cs = append(cs, cv)
+	}
+	for _, idx := range shrinkLen(len(rs)) {
+		c := make([]rune, 0, len(idx))
+		for _, i := range idx {
+			c = append(c, rs[i])
+		}
+		add(c)
+	}
+	for i, r := range rs {
+		if r != simplest {
+			c := append([]rune(nil), rs...)
+			c[i] = simplest
+			add(c)
+		}
+	}
+	return cs
+}
+
+// maxShrinkRemoves limit candidates of removing single element
+const maxShrinkRemoves = 32
+
+// shrinkLen return indexes of elements to keep for shorter candidates: empty,
+// first half, second half, and remove single element
+func shrinkLen(n int) [][]int {
+	if n == 0 {
+		return nil
+	}
+
+	seq := func(begin, end int) []int {
+		idx := make([]int, 0, end-begin)
+		for i := begin; i < end; i++ {
+			idx = append(idx, i)
+		}
+		return idx
+	}
+	cs := [][]int{nil}
+	if n > 2 {
+		cs = append(cs, seq(0, n/2), seq(n/2, n))
+	}
+	if n > 1 {
+		for i := 0; i < n && i < maxShrinkRemoves; i++ {
+			cs = append(cs, append(seq(0, i), seq(i+1, n)...))
+		}
+	}
+	return cs
+}
+
+type sliceGen struct {
+	typ    reflect.Type
+	elem   Gen
+	maxLen int
+}
+
+func (g sliceGen) Type() reflect.Type { return g.typ }
+
+func (g sliceGen) Generate(r *rand.Rand, size int) reflect.Value {
+	max := g.maxLen
+	if max <= 0 {
+		max = size
+	}
+
+	n := r.Intn(max + 1)
+	if exhausted(g.elem, size) {
+		n = 0
+	}
+	v := reflect.MakeSlice(g.typ, n, n)
+	esize := elemSize(g.elem, size, n)
+	for i := 0; i < n; i++ {
+		v.Index(i).Set(g.elem.Generate(r, esize))
+	}
+	return v
+}
+
+func (g sliceGen) Shrink(v reflect.Value) []reflect.Value {
+	if v.IsNil() {
+		return nil
+	}
+
+	var cs []reflect.Value
+	for _, idx := range shrinkLen(v.Len()) {
+		c := reflect.MakeSlice(g.typ, 0, len(idx))
+		for _, i := range idx {
+			c = reflect.Append(c, v.Index(i))
+		}
+		cs = append(cs, c)
+	}
+	return append(cs, shrinkElems(v, g.elem, func() reflect.Value {
+		c := reflect.MakeSlice(g.typ, v.Len(), v.Len())
+		reflect.Copy(c, v)
+		return c
+	})...)
+}
+
+// shrinkElems replace each element with it's first few shrink candidates
+func shrinkElems(v reflect.Value, elem Gen, clone func() reflect.Value) []reflect.Value {
+	var cs []reflect.Value
+	for i := 0; i < v.Len(); i++ {
+		for j, e := range elem.Shrink(v.Index(i)) {
+			if j == 3 {
+				break
+			}
+			c := clone()
+			c.Index(i).Set(e)
+			cs = append(cs, c)
+		}
+	}
+	return cs
+}
+
+type arrayGen struct {
+	typ  reflect.Type
+	elem Gen
+}
+
+func (g arrayGen) Type() reflect.Type { return g.typ }
+
+func (g arrayGen) Generate(r *rand.Rand, size int) reflect.Value {
+	v := reflect.New(g.typ).Elem()
+	for i := 0; i < v.Len(); i++ {
+		v.Index(i).Set(g.elem.Generate(r, size))
+	}
+	return v
+}
+
+func (g arrayGen) Shrink(v reflect.Value) []reflect.Value {
+	return shrinkElems(v, g.elem, func() reflect.Value {
+		c := reflect.New(g.typ).Elem()
+		c.Set(v)
+		return c
+	})
+}
+
+type mapGen struct {
+	typ      reflect.Type
+	key, val Gen
+	maxLen   int
+}
+
+func (g mapGen) Type() reflect.Type { return g.typ }
+
+func (g mapGen) Generate(r *rand.Rand, size int) reflect.Value {
+	max := g.maxLen
+	if max <= 0 {
+		max = size
+	}
+
+	v := reflect.MakeMap(g.typ)
+	if exhausted(g.key, size) || exhausted(g.val, size) {
+		return v
+	}
+	n := r.Intn(max + 1)
+	ksize, vsize := elemSize(g.key, size, n), elemSize(g.val, size, n)
+	for ; n > 0; n-- {
+		v.SetMapIndex(g.key.Generate(r, ksize), g.val.Generate(r, vsize))
+	}
+	return v
+}
+
+func (g mapGen) Shrink(v reflect.Value) []reflect.Value {
+	if v.IsNil() || v.Len() == 0 {
+		return nil
+	}
+
+	keys := sortedKeys(v, v)
+	clone := func(skip int) reflect.Value {
+		c := reflect.MakeMap(g.typ)
+		for i, k := range keys {
+			if i != skip {
+				c.SetMapIndex(k, v.MapIndex(k))
+			}
+		}
+		return c
+	}
+
+	cs := []reflect.Value{reflect.MakeMap(g.typ)}
+	for i := range keys {
+		if len(keys) > 1 && i < maxShrinkRemoves {
+			cs = append(cs, clone(i))
+		}
+	}
+	for i, k := range keys {
+		for j, e := range g.val.Shrink(v.MapIndex(k)) {
+			if j == 3 {
+				break
+			}
+			c := clone(-1)
+			c.SetMapIndex(keys[i], e)
+			cs = append(cs, c)
+		}
+	}
+	return cs
+}
+
+type ptrGen struct {
+	typ  reflect.Type
+	elem Gen
+}
+
+func (g ptrGen) Type() reflect.Type { return g.typ }
+
+func (g ptrGen) Generate(r *rand.Rand, size int) reflect.Value {
+	if exhausted(g.elem, size) {
+		return reflect.Zero(g.typ)
+	}
+	v := reflect.New(g.typ.Elem())
+	v.Elem().Set(g.elem.Generate(r, size))
+	return v
+}
+
+func (g ptrGen) Shrink(v reflect.Value) []reflect.Value {
+	if v.IsNil() {
+		return nil
+	}
+
+	var cs []reflect.Value
+	for _, e := range g.elem.Shrink(v.Elem()) {
+		c := reflect.New(g.typ.Elem())
+		c.Elem().Set(e)
+		cs = append(cs, c)
+	}
+	return cs
+}
+
+type structGen struct {
+	typ    reflect.Type
+	fields []Gen
+}
+
+func (g structGen) Type() reflect.Type { return g.typ }
+
+func (g structGen) Generate(r *rand.Rand, size int) reflect.Value {
+	v := reflect.New(g.typ).Elem()
+	for i, f := range g.fields {
+		if f != nil {
+			v.Field(i).Set(f.Generate(r, size))
+		}
+	}
+	return v
+}
+
+func (g structGen) Shrink(v reflect.Value) []reflect.Value {
+	var cs []reflect.Value
+	for i, f := range g.fields {
+		if f == nil {
+			continue
+		}
+		for _, e := range f.Shrink(v.Field(i)) {
+			c := reflect.New(g.typ).Elem()
+			c.Set(v)
+			c.Field(i).Set(e)
+			cs = append(cs, c)
+		}
+	}
+	return cs
+}
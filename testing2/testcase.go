@@ -3,7 +3,10 @@ package testing2
 import (
 	"fmt"
 	"reflect"
+	"strconv"
 	"testing"
+
+	"github.com/cosiner/gohper/runtime2"
 )
 
 // NoCheck means don't check this value
@@ -14,6 +17,7 @@ var NonNil = struct{}{}
 
 type TestCase struct {
 	state   bool
+	names   []string
 	expects [][]interface{}
 	args    [][]reflect.Value
 }
@@ -45,6 +49,18 @@ func (test *TestCase) Expect(expect ...interface{}) *TestCase {
 	test.checkState(wantExpect)
 
 	test.expects = append(test.expects, expect)
+	test.names = append(test.names, "")
+	return test
+}
+
+// Name set name of current case, it's used as subtest name, default is the
+// case index start from 1
+func (test *TestCase) Name(name string) *TestCase {
+	if len(test.names) == 0 {
+		panic("call Expect before Name")
+	}
+
+	test.names[len(test.names)-1] = name
 	return test
 }
 
@@ -73,47 +89,52 @@ func (test *TestCase) Arg(args ...interface{}) *TestCase {
 	return test
 }
 
+// Run call fn with each case's arguments, if there are multiple functions, the
+// results of previous function are passed to next one. If t is a *testing.T,
+// each case is run as a named subtest.
 func (test *TestCase) Run(t testing.TB, fn ...interface{}) *TestCase {
+	pos := runtime2.Caller(1)
 	for index := range test.expects {
-		var expects = test.expects[index]
-
-		var results = test.args[index]
-		for _, f := range fn {
-			results = reflect.ValueOf(f).Call(results)
+		name := test.names[index]
+		if name == "" {
+			name = strconv.Itoa(index + 1)
 		}
 
-		for i, r := range results {
-			if expect := expects[i]; expect != NoCheck {
-				indexs := fmt.Sprintf("(%d:%d)", index+1, i+1)
-				if expect == nil {
-					indexNil(t, 1, indexs, "nil", r.Interface())
-				} else if expect == NonNil {
-					indexNil(t, 1, indexs, "not nil", "nil")
-				} else {
-					indexDeepEq(t, 1, indexs, expect, r.Interface())
-				}
-			}
+		if tt, is := t.(*testing.T); is {
+			tt.Run(name, func(t *testing.T) {
+				test.runCase(t, pos, index, name, fn)
+			})
+		} else {
+			test.runCase(t, pos, index, name, fn)
 		}
 	}
 
 	return test
 }
 
-func indexNil(t testing.TB, skip int, index string, expect, got interface{}) {
-	if !isNil(got) {
-		indexErrorInfo(t, skip+1, index, expect, got, false)
-	}
-}
+func (test *TestCase) runCase(t testing.TB, pos string, index int, name string, fn []interface{}) {
+	var expects = test.expects[index]
 
-func indexNNil(t testing.TB, skip int, index string, expect, got interface{}) {
-	if isNil(got) {
-		indexErrorInfo(t, skip+1, index, expect, got, false)
+	var results = test.args[index]
+	for _, f := range fn {
+		results = reflect.ValueOf(f).Call(results)
 	}
-}
 
-// indexEq assert expect and got is equal, else print error message
-func indexDeepEq(t testing.TB, skip int, index string, expect, got interface{}) {
-	if !reflect.DeepEqual(expect, got) {
-		indexErrorInfo(t, skip+1, index, expect, got, true)
+	for i, r := range results {
+		if expect := expects[i]; expect != NoCheck {
+			indexs := fmt.Sprintf("(%s:%d)", name, i+1)
+			got := r.Interface()
+			if expect == nil {
+				if !isNil(got) {
+					posErrorDetail(t, pos, indexs, "nil", got, false, "")
+				}
+			} else if expect == NonNil {
+				if isNil(got) {
+					posErrorDetail(t, pos, indexs, "not nil", "nil", false, "")
+				}
+			} else if !reflect.DeepEqual(expect, got) {
+				posErrorDetail(t, pos, indexs, expect, got, true, diffDetail(expect, got))
+			}
+		}
 	}
 }
@@ -144,7 +144,7 @@ func eq(t testing.TB, skip int, expect, got interface{}) {
 // deepEq assert expect and got is deep-equal, else print error message
 func deepEq(t testing.TB, skip int, expect, got interface{}) {
 	if !reflect.DeepEqual(expect, got) {
-		errorInfo(t, skip+1, expect, got, true)
+		indexDiffInfo(t, skip+1, "", expect, got)
 	}
 }
 
@@ -197,10 +197,28 @@ var (
 )
 
 func indexErrorInfo(t testing.TB, skip int, index string, expect, got interface{}, withType bool) {
-	var (
-		pos        = runtime2.Caller(skip + 1)
-		exps, gots string
-	)
+	indexErrorDetail(t, skip+1, index, expect, got, withType, "")
+}
+
+// indexDiffInfo print error message with structural differences
+func indexDiffInfo(t testing.TB, skip int, index string, expect, got interface{}) {
+	indexErrorDetail(t, skip+1, index, expect, got, true, diffDetail(expect, got))
+}
+
+func diffDetail(expect, got interface{}) string {
+	if detail := Diff(expect, got); detail != "" {
+		return "\n" + detail
+	}
+
+	return ""
+}
+
+func indexErrorDetail(t testing.TB, skip int, index string, expect, got interface{}, withType bool, detail string) {
+	posErrorDetail(t, runtime2.Caller(skip+1), index, expect, got, withType, detail)
+}
+
+func posErrorDetail(t testing.TB, pos string, index string, expect, got interface{}, withType bool, detail string) {
+	var exps, gots string
 
 	const formatT = "%+v(%T)"
 	const format = "%+v"
@@ -231,7 +249,7 @@ func indexErrorInfo(t testing.TB, skip int, index string, expect, got interface{
 		pos += ": " + index
 	}
 
-	t.Errorf("%s: expect: %s, got: %s", pos, exps, gots)
+	t.Errorf("%s: expect: %s, got: %s%s", pos, exps, gots, detail)
 }
 
 func isNil(v interface{}) bool {
@@ -1,8 +1,18 @@
 package testing2
 
 import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestTest(t *testing.T) {
@@ -37,6 +47,250 @@ func TestTest(t *testing.T) {
 func TestTestCase(t *testing.T) {
 	Tests().
 		Expect("abc").Arg("  abc   ").
-		Expect("ab c").Arg("  ab c   ").
+		Expect("ab c").Name("inner space").Arg("  ab c   ").
 		Run(t, strings.TrimSpace)
+
+	rt := &recordTB{TB: t}
+	Expect("a").Name("trim").Arg(" b ").
+		Expect(NoCheck).Arg(" c ").
+		Run(rt, strings.TrimSpace)
+	Eq(t, 1, len(rt.msgs))
+	True(t, strings.Contains(rt.msgs[0], "(trim:1): expect: a(string), got: b(string)"))
+}
+
+// recordTB record failure messages instead of fail the test
+type recordTB struct {
+	testing.TB
+	msgs []string
+}
+
+func (t *recordTB) Errorf(format string, args ...interface{}) {
+	t.msgs = append(t.msgs, fmt.Sprintf(format, args...))
+}
+
+type user struct {
+	Name  string
+	Tags  []string
+	Attrs map[string]int
+	next  *user
+}
+
+func TestDiff(t *testing.T) {
+	tt := Wrap(t)
+
+	tt.Eq("", Diff([]int{1}, []int{1}))
+	tt.Eq("value: expect nil([]uint8), got []", Diff([]byte(nil), []byte{}))
+	tt.Eq("value: expect 1(int), got 1(int64)", Diff(1, int64(1)))
+
+	expect := &user{Name: "a", Tags: []string{"x", "y"}, Attrs: map[string]int{"k": 1, "m": 2}, next: &user{Name: "b"}}
+	got := &user{Name: "a", Tags: []string{"x"}, Attrs: map[string]int{"k": 2, "n": 3}, next: &user{Name: "c"}}
+	tt.Eq(`.Tags[1]: expect "y", got <missing>
+.Attrs["k"]: expect 1, got 2
+.Attrs["m"]: expect 2, got <missing>
+.Attrs["n"]: expect <missing>, got 3
+.next.Name: expect "b", got "c"`, Diff(expect, got))
+
+	loop := &user{}
+	loop.next = loop
+	tt.Eq("", Diff(loop, loop))
+
+	rt := &recordTB{TB: t}
+	DeepEq(rt, expect, got)
+	tt.Eq(1, len(rt.msgs))
+	tt.True(strings.HasSuffix(rt.msgs[0], `.next.Name: expect "b", got "c"`))
+}
+
+func TestAssert(t *testing.T) {
+	errWrapped := fmt.Errorf("wrap: %w", io.EOF)
+
+	Wrap(t).
+		ErrorIs(errWrapped, io.EOF).
+		ErrorContains(errWrapped, "wrap").
+		Contains("abc", "b").
+		Contains([]int{1, 2}, 2).
+		Contains(map[string]int{"a": 1}, "a").
+		Len("abc", 3).
+		Len(map[int]int{1: 1}, 1).
+		InDelta(1.0, 1.05, 0.1).
+		JSONEq(`{"a": [1, 2], "b": {"c": null}}`, `{"b":{"c":null},"a":[1,2]}`).
+		Panics(func() { panic(errWrapped) }, io.EOF).
+		Panics(func() { panic("x") }, "x").
+		Panics(func() { panic(nil) }, nil)
+
+	n := 0
+	Eventually(t, func() bool {
+		n++
+		return n == 3
+	}, time.Second, time.Millisecond)
+
+	rt := &recordTB{TB: t}
+	Wrap(rt).
+		ErrorIs(errWrapped, io.ErrUnexpectedEOF).
+		ErrorContains(nil, "a").
+		Contains([]int{1}, 2).
+		Contains(1, 1).
+		Len([]int{}, 1).
+		InDelta(1, 2, 0.5).
+		InDelta(1, math.NaN(), 0.5).
+		JSONEq(`{"a":1}`, `{"a":2}`).
+		Panics(func() {}, nil).
+		Panics(func() { panic("y") }, "x").
+		Eventually(func() bool { return false }, 5*time.Millisecond, time.Millisecond)
+	Eq(t, 11, len(rt.msgs))
+	True(t, strings.HasSuffix(rt.msgs[7], `["a"]: expect 1, got 2`))
+}
+
+type point struct {
+	X, Y int
+	tag  string
+}
+
+func TestProperty(t *testing.T) {
+	Check(t, func(xs []int, s string) bool {
+		ys := append([]int(nil), xs...)
+		sort.Ints(ys)
+		return sort.IntsAreSorted(ys) && len(ys) == len(xs) && strings.ToUpper(strings.ToLower(s)) == strings.ToUpper(s)
+	})
+	Check(t, func(p point, m map[string]bool, f float64) error {
+		if p.tag != "" || f < -100 || f > 100 {
+			return fmt.Errorf("unexpected value: %v %v", p, f)
+		}
+		return nil
+	})
+	Check(t, func(n int, s string) bool {
+		return n >= 1 && n <= 3 && strings.Trim(s, "ab") == ""
+	}, IntRange(1, 3), Strings("ab", 5))
+
+	rt := &recordTB{TB: t}
+	Property{Seed: 1}.Check(rt, func(xs []int) bool {
+		for _, x := range xs {
+			if x > 10 {
+				return false
+			}
+		}
+		return true
+	})
+	Eq(t, 1, len(rt.msgs))
+	True(t, strings.Contains(rt.msgs[0], "(seed 1, rerun with -testing2.seed=1)"))
+	True(t, strings.Contains(rt.msgs[0], "): ([]int{11})"))
+
+	rt.msgs = nil
+	Property{Gens: []Gen{StructOf(point{}, map[string]Gen{"Y": IntRange(5, 10)})}}.Check(rt, func(p point) bool {
+		if p.Y > 7 {
+			panic("too large")
+		}
+		return true
+	})
+	Eq(t, 1, len(rt.msgs))
+	True(t, strings.Contains(rt.msgs[0], "X:0, Y:8"))
+	True(t, strings.Contains(rt.msgs[0], "error: panic: too large"))
+}
+
+type node struct {
+	Val  int
+	Next *node
+	Kids []node
+	Refs map[string]*node
+}
+
+func nodeDepth(n *node) int {
+	if n == nil {
+		return 0
+	}
+	d := nodeDepth(n.Next)
+	for i := range n.Kids {
+		if kd := nodeDepth(&n.Kids[i]); kd > d {
+			d = kd
+		}
+	}
+	for _, r := range n.Refs {
+		if rd := nodeDepth(r); rd > d {
+			d = rd
+		}
+	}
+	return d + 1
+}
+
+func TestPropertyRecursive(t *testing.T) {
+	gen := TypeGen(reflect.TypeOf(node{}))
+	r := rand.New(rand.NewSource(1))
+	for size := 0; size <= 100; size++ {
+		n := gen.Generate(r, size).Interface().(node)
+		True(t, nodeDepth(&n) <= 8)
+	}
+
+	n := gen.Generate(r, 1).Interface().(node)
+	True(t, n.Next == nil)
+	Eq(t, 0, len(n.Kids))
+	for _, r := range n.Refs {
+		True(t, r == nil)
+	}
+
+	rt := &recordTB{TB: t}
+	Property{Runs: 50, Seed: 1}.Check(rt, func(n *node) bool {
+		return n == nil || n.Val <= 10
+	})
+	Eq(t, 1, len(rt.msgs))
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tt := Wrap(t)
+
+	tt.Eq("", UnifiedDiff("a", "b", "x\n", "x\n"))
+	expect := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	got := "1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n11\n12\n13\n"
+	tt.Eq(`--- expect
++++ got
+@@ -2,7 +2,7 @@
+ 2
+ 3
+ 4
+-5
++five
+ 6
+ 7
+ 8
+@@ -10,3 +10,4 @@
+ 10
+ 11
+ 12
++13
+`, UnifiedDiff("expect", "got", expect, got))
+}
+
+func TestGolden(t *testing.T) {
+	dir, err := ioutil.TempDir("", "golden")
+	Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	GoldenDir = dir
+	defer func() {
+		GoldenDir = "testdata"
+	}()
+
+	rt := &recordTB{TB: t}
+	Golden(rt, "text", []byte("a\r\nb\n"))
+	Eq(t, 1, len(rt.msgs))
+	True(t, strings.Contains(rt.msgs[0], "run test with -update"))
+
+	Nil(t, flag.Set("update", "true"))
+	Golden(t, "text", []byte("a\r\nb\n"))
+	GoldenJSON(t, "json", []byte(`{"b": [1, 2], "a": "x"}`))
+	Nil(t, flag.Set("update", "false"))
+
+	data, err := ioutil.ReadFile(GoldenFile("json"))
+	Nil(t, err).Eq("{\n  \"a\": \"x\",\n  \"b\": [\n    1,\n    2\n  ]\n}\n", string(data))
+
+	rt.msgs = nil
+	Wrap(rt).
+		Golden("text", []byte("a\nb\n")).
+		GoldenJSON("json", []byte(`{"a":"x","b":[1,2]}`))
+	Eq(t, 0, len(rt.msgs))
+
+	Wrap(rt).
+		Golden("text", []byte("a\nc\n")).
+		GoldenJSON("json", []byte(`{"a":"x","b":[1,3]}`))
+	Eq(t, 2, len(rt.msgs))
+	True(t, strings.HasSuffix(rt.msgs[0], "@@ -1,2 +1,2 @@\n a\n-b\n+c\n"))
+	True(t, strings.Contains(rt.msgs[1], "-    2\n+    3\n"))
 }
@@ -0,0 +1,145 @@
+package time2
+
+import "time"
+
+// Holidays decide whether a date is a holiday
+type Holidays interface {
+	IsHoliday(d LocalDate) bool
+}
+
+// HolidayFunc is a function implements Holidays
+type HolidayFunc func(d LocalDate) bool
+
+func (fn HolidayFunc) IsHoliday(d LocalDate) bool {
+	return fn(d)
+}
+
+// HolidayList is a fixed set of holidays
+type HolidayList map[LocalDate]bool
+
+func NewHolidayList(dates ...LocalDate) HolidayList {
+	l := make(HolidayList, len(dates))
+	for _, d := range dates {
+		l[d] = true
+	}
+	return l
+}
+
+func (l HolidayList) Add(dates ...LocalDate) HolidayList {
+	for _, d := range dates {
+		l[d] = true
+	}
+	return l
+}
+
+func (l HolidayList) IsHoliday(d LocalDate) bool {
+	return l[d]
+}
+
+// Annual return holidays of a same day every year, such as New Year's Day
+func Annual(month time.Month, day int) Holidays {
+	return HolidayFunc(func(d LocalDate) bool {
+		return d.Month == month && d.Day == day
+	})
+}
+
+// BusinessCalendar count and add working days, a working day is neither a
+// weekend day nor a holiday
+type BusinessCalendar struct {
+	// Weekend default Saturday and Sunday
+	Weekend  []time.Weekday
+	Holidays []Holidays
+}
+
+// NewBusinessCalendar create a calendar with default weekend
+func NewBusinessCalendar(holidays ...Holidays) *BusinessCalendar {
+	return &BusinessCalendar{Holidays: holidays}
+}
+
+func (c *BusinessCalendar) IsWeekend(d LocalDate) bool {
+	wd := d.Weekday()
+	if c.Weekend == nil {
+		return wd == time.Saturday || wd == time.Sunday
+	}
+	for _, w := range c.Weekend {
+		if w == wd {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *BusinessCalendar) IsHoliday(d LocalDate) bool {
+	for _, h := range c.Holidays {
+		if h.IsHoliday(d) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *BusinessCalendar) IsBusinessDay(d LocalDate) bool {
+	return !c.IsWeekend(d) && !c.IsHoliday(d)
+}
+
+// NextBusinessDay return the first business day on or after d
+func (c *BusinessCalendar) NextBusinessDay(d LocalDate) LocalDate {
+	c.checkWeekend()
+	for !c.IsBusinessDay(d) {
+		d = d.AddDays(1)
+	}
+	return d
+}
+
+// PrevBusinessDay return the last business day on or before d
+func (c *BusinessCalendar) PrevBusinessDay(d LocalDate) LocalDate {
+	c.checkWeekend()
+	for !c.IsBusinessDay(d) {
+		d = d.AddDays(-1)
+	}
+	return d
+}
+
+// AddBusinessDays move n business days from d, backward if n is negative.
+// If n is 0, d is returned even if it's not a business day.
+func (c *BusinessCalendar) AddBusinessDays(d LocalDate, n int) LocalDate {
+	c.checkWeekend()
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	for n > 0 {
+		d = d.AddDays(step)
+		if c.IsBusinessDay(d) {
+			n--
+		}
+	}
+	return d
+}
+
+// BusinessDays count business days in [from, to), it's negative if to is
+// before from
+func (c *BusinessCalendar) BusinessDays(from, to LocalDate) int {
+	if to.Before(from) {
+		return -c.BusinessDays(to, from)
+	}
+	var n int
+	for d := from; d.Before(to); d = d.AddDays(1) {
+		if c.IsBusinessDay(d) {
+			n++
+		}
+	}
+	return n
+}
+
+func (c *BusinessCalendar) checkWeekend() {
+	if len(c.Weekend) >= 7 {
+		days := make(map[time.Weekday]bool)
+		for _, w := range c.Weekend {
+			days[w] = true
+		}
+		if len(days) >= 7 {
+			panic("time2: every day is weekend")
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package time2
+
+import "time"
+
+const LOCAL_DATE_FMT = "2006-01-02"
+
+// LocalDate is a date without time and location
+type LocalDate struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// NewLocalDate create a date, overflowed fields are normalized like time.Date,
+// such as 2015-02-30 become 2015-03-02
+func NewLocalDate(year int, month time.Month, day int) LocalDate {
+	return DateOf(time.Date(year, month, day, 0, 0, 0, 0, time.UTC))
+}
+
+// DateOf return date part of the time in its location
+func DateOf(t time.Time) LocalDate {
+	y, m, d := t.Date()
+	return LocalDate{Year: y, Month: m, Day: d}
+}
+
+// Today return current date in Location
+func Today() LocalDate {
+	return DateOf(Now())
+}
+
+// ParseLocalDate parse date in format "2006-01-02"
+func ParseLocalDate(s string) (LocalDate, error) {
+	t, err := time.Parse(LOCAL_DATE_FMT, s)
+	if err != nil {
+		return LocalDate{}, err
+	}
+	return DateOf(t), nil
+}
+
+func (d LocalDate) String() string {
+	return d.In(time.UTC).Format(LOCAL_DATE_FMT)
+}
+
+func (d LocalDate) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *LocalDate) UnmarshalText(data []byte) error {
+	date, err := ParseLocalDate(string(data))
+	if err == nil {
+		*d = date
+	}
+	return err
+}
+
+// IsValid check whether the date is a existed day
+func (d LocalDate) IsValid() bool {
+	return NewLocalDate(d.Year, d.Month, d.Day) == d
+}
+
+// In return start of the day in given location
+func (d LocalDate) In(loc *time.Location) time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, loc)
+}
+
+func (d LocalDate) Weekday() time.Weekday {
+	return d.In(time.UTC).Weekday()
+}
+
+// ISOWeek return ISO 8601 year and week number of the date
+func (d LocalDate) ISOWeek() (year, week int) {
+	return d.In(time.UTC).ISOWeek()
+}
+
+// YearDay return day of the year, in range [1, 366]
+func (d LocalDate) YearDay() int {
+	return d.In(time.UTC).YearDay()
+}
+
+func (d LocalDate) AddDays(n int) LocalDate {
+	return NewLocalDate(d.Year, d.Month, d.Day+n)
+}
+
+// AddMonths add months to the date, the day is clamped to the last day of
+// target month, such as 01-31 + 1 month is 02-28 or 02-29
+func (d LocalDate) AddMonths(n int) LocalDate {
+	y, m := addMonths(d.Year, d.Month, n)
+	day := d.Day
+	if days := MonthDays(y, int(m)); day > days {
+		day = days
+	}
+	return LocalDate{Year: y, Month: m, Day: day}
+}
+
+func (d LocalDate) AddYears(n int) LocalDate {
+	return d.AddMonths(12 * n)
+}
+
+// DaysSince return days from u to d, negative if d is before u
+func (d LocalDate) DaysSince(u LocalDate) int {
+	return int(d.In(time.UTC).Sub(u.In(time.UTC)) / Day)
+}
+
+func (d LocalDate) Before(u LocalDate) bool {
+	return d.Compare(u) < 0
+}
+
+func (d LocalDate) After(u LocalDate) bool {
+	return d.Compare(u) > 0
+}
+
+// Compare return -1, 0, 1 if d is before, equal to, after u
+func (d LocalDate) Compare(u LocalDate) int {
+	switch {
+	case d.Year != u.Year:
+		return cmpInt(d.Year, u.Year)
+	case d.Month != u.Month:
+		return cmpInt(int(d.Month), int(u.Month))
+	default:
+		return cmpInt(d.Day, u.Day)
+	}
+}
+
+func cmpInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func addMonths(year int, month time.Month, n int) (int, time.Month) {
+	m := int(month) - 1 + n
+	year += m / 12
+	if m %= 12; m < 0 {
+		m += 12
+		year--
+	}
+	return year, time.Month(m + 1)
+}
+
+// AddMonths add months to the time, the day is clamped to the last day of
+// target month, clock and location are kept
+func AddMonths(t time.Time, n int) time.Time {
+	d := DateOf(t).AddMonths(n)
+	hour, min, sec := t.Clock()
+	return time.Date(d.Year, d.Month, d.Day, hour, min, sec, t.Nanosecond(), t.Location())
+}
+
+// StartOfDay return the first moment of the day in t's location
+func StartOfDay(t time.Time) time.Time {
+	return DateOf(t).In(t.Location())
+}
+
+// EndOfDay return the last nanosecond of the day in t's location
+func EndOfDay(t time.Time) time.Time {
+	return DateOf(t).AddDays(1).In(t.Location()).Add(-1)
+}
+
+// StartOfWeek return start of the week, weeks begin at firstDay
+func StartOfWeek(t time.Time, firstDay time.Weekday) time.Time {
+	d := DateOf(t)
+	offset := (int(d.Weekday()) - int(firstDay) + 7) % 7
+	return d.AddDays(-offset).In(t.Location())
+}
+
+// EndOfWeek return the last nanosecond of the week, weeks begin at firstDay
+func EndOfWeek(t time.Time, firstDay time.Weekday) time.Time {
+	return DateOf(StartOfWeek(t, firstDay)).AddDays(7).In(t.Location()).Add(-1)
+}
+
+func StartOfMonth(t time.Time) time.Time {
+	y, m, _ := t.Date()
+	return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+}
+
+func EndOfMonth(t time.Time) time.Time {
+	y, m, _ := t.Date()
+	return time.Date(y, m+1, 1, 0, 0, 0, 0, t.Location()).Add(-1)
+}
+
+// Quarter return quarter of the time, in range [1, 4]
+func Quarter(t time.Time) int {
+	return (int(t.Month())-1)/3 + 1
+}
+
+func StartOfQuarter(t time.Time) time.Time {
+	m := time.Month((Quarter(t)-1)*3 + 1)
+	return time.Date(t.Year(), m, 1, 0, 0, 0, 0, t.Location())
+}
+
+func EndOfQuarter(t time.Time) time.Time {
+	m := time.Month(Quarter(t)*3 + 1)
+	return time.Date(t.Year(), m, 1, 0, 0, 0, 0, t.Location()).Add(-1)
+}
+
+// ISOWeekStart return the monday of ISO 8601 week in given year
+func ISOWeekStart(year, week int) LocalDate {
+	// January 4th is always in week 1
+	jan4 := LocalDate{Year: year, Month: time.January, Day: 4}
+	offset := (int(jan4.Weekday()) + 6) % 7
+	return jan4.AddDays(-offset + (week-1)*7)
+}
+
+// ISOWeeksInYear return count of ISO 8601 weeks in the year, 52 or 53
+func ISOWeeksInYear(year int) int {
+	_, week := LocalDate{Year: year, Month: time.December, Day: 28}.ISOWeek()
+	return week
+}
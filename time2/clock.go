@@ -0,0 +1,252 @@
+package time2
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock abstract time operations, use FakeClock in tests to control time
+// without sleeping
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	Sleep(d time.Duration)
+	After(d time.Duration) <-chan time.Time
+	NewTimer(d time.Duration) Timer
+	NewTicker(d time.Duration) Ticker
+}
+
+type Timer interface {
+	C() <-chan time.Time
+	// Stop and Reset have same semantic as time.Timer
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+	Reset(d time.Duration)
+}
+
+// RealClock is the clock backed by package time, Now honors Location
+var RealClock Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return Now() }
+func (realClock) Since(t time.Time) time.Duration        { return Since(t) }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
+
+// FakeClock is a clock only advanced by Add and Set, timers and tickers fire
+// when the time reaches their deadlines. Like package time, channels are
+// buffered by one and ticks are dropped for slow receivers.
+type FakeClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+type fakeWaiter struct {
+	clock  *FakeClock
+	at     time.Time
+	period time.Duration
+	ch     chan time.Time
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	c := &FakeClock{now: now}
+	c.cond = sync.NewCond(&c.mu)
+
+	return c
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *FakeClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// Add advance the clock and fire timers in deadline order
+func (c *FakeClock) Add(d time.Duration) {
+	c.Set(c.Now().Add(d))
+}
+
+// Set the clock to t, it can't go backward
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for {
+		var next *fakeWaiter
+		for _, w := range c.waiters {
+			if !w.at.After(t) && (next == nil || w.at.Before(next.at)) {
+				next = w
+			}
+		}
+		if next == nil {
+			break
+		}
+
+		if next.at.After(c.now) {
+			c.now = next.at
+		}
+		select {
+		case next.ch <- c.now:
+		default:
+		}
+		if next.period > 0 {
+			next.at = next.at.Add(next.period)
+		} else {
+			c.remove(next)
+		}
+	}
+	if t.After(c.now) {
+		c.now = t
+	}
+}
+
+// Waiters return count of active timers, tickers and sleepers
+func (c *FakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.waiters)
+}
+
+// BlockUntil block until there are at least n active timers, tickers and
+// sleepers, it's used to make sure goroutines are waiting before Add
+func (c *FakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	for len(c.waiters) < n {
+		c.cond.Wait()
+	}
+	c.mu.Unlock()
+}
+
+func (c *FakeClock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	w := &fakeWaiter{clock: c, ch: make(chan time.Time, 1)}
+	w.Reset(d)
+
+	return fakeTimer{w}
+}
+
+func (c *FakeClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+
+	w := &fakeWaiter{clock: c, ch: make(chan time.Time, 1)}
+	w.reset(d, d)
+
+	return fakeTicker{w}
+}
+
+func (c *FakeClock) add(w *fakeWaiter) bool {
+	for _, v := range c.waiters {
+		if v == w {
+			return true
+		}
+	}
+	c.waiters = append(c.waiters, w)
+	c.cond.Broadcast()
+
+	return false
+}
+
+func (c *FakeClock) remove(w *fakeWaiter) bool {
+	for i, v := range c.waiters {
+		if v == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w *fakeWaiter) C() <-chan time.Time {
+	return w.ch
+}
+
+func (w *fakeWaiter) Stop() bool {
+	c := w.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.remove(w)
+}
+
+func (w *fakeWaiter) Reset(d time.Duration) bool {
+	return w.reset(d, 0)
+}
+
+func (w *fakeWaiter) reset(d, period time.Duration) bool {
+	c := w.clock
+	c.mu.Lock()
+	w.at = c.now.Add(d)
+	w.period = period
+	active := c.add(w)
+	c.mu.Unlock()
+
+	if d <= 0 {
+		// fire immediately like time.Timer
+		c.Set(c.Now())
+	}
+	return active
+}
+
+type fakeTimer struct {
+	*fakeWaiter
+}
+
+type fakeTicker struct {
+	*fakeWaiter
+}
+
+func (t fakeTicker) Stop() {
+	t.fakeWaiter.Stop()
+}
+
+func (t fakeTicker) Reset(d time.Duration) {
+	t.reset(d, d)
+}
@@ -0,0 +1,391 @@
+package time2
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cosiner/gohper/errors"
+)
+
+// ParseError report the offending position of input
+type ParseError struct {
+	Input string
+	Pos   int
+	Msg   string
+}
+
+func (e *ParseError) Error() string {
+	return "time2: " + e.Msg + " at position " + strconv.Itoa(e.Pos) + " of " + strconv.Quote(e.Input)
+}
+
+// Schedule decide next run time of a job
+type Schedule interface {
+	// Next return the first run time after t, zero time means never
+	Next(t time.Time) time.Time
+}
+
+// Every is a schedule run at fixed interval, it's rounded to second if it's
+// longer than one second
+type Every time.Duration
+
+func (e Every) Next(t time.Time) time.Time {
+	d := time.Duration(e)
+	if d >= time.Second {
+		return t.Add(d - time.Duration(t.Nanosecond()))
+	}
+	return t.Add(d)
+}
+
+// CronSchedule is a parsed cron expression
+type CronSchedule struct {
+	second, minute, hour, dom, month, dow uint64
+	// Location of the schedule, nil means use package Location
+	Location *time.Location
+}
+
+type cronField struct {
+	name     string
+	min, max uint
+	names    []string
+}
+
+var (
+	cronSecond = cronField{name: "second", min: 0, max: 59}
+	cronMinute = cronField{name: "minute", min: 0, max: 59}
+	cronHour   = cronField{name: "hour", min: 0, max: 23}
+	cronDom    = cronField{name: "day of month", min: 1, max: 31}
+	cronMonth  = cronField{name: "month", min: 1, max: 12, names: []string{
+		"", "jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec",
+	}}
+	// 7 is also sunday
+	cronDow = cronField{name: "day of week", min: 0, max: 7, names: []string{
+		"sun", "mon", "tue", "wed", "thu", "fri", "sat",
+	}}
+)
+
+// starBit mark a field is "*" or "?", it's used to decide how day of month
+// and day of week are combined
+const starBit = 1 << 63
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// ParseCron parse cron expression, supported formats:
+//
+//	minute hour day-of-month month day-of-week
+//	second minute hour day-of-month month day-of-week
+//	@yearly, @annually, @monthly, @weekly, @daily, @midnight, @hourly
+//	@every 1h30m
+//
+// Fields accept "*", "?", values, ranges "a-b", steps "*/n" "a-b/n" "a/n" and
+// comma separated lists, month and day of week also accept english short
+// names. If both day of month and day of week are restricted, either matched
+// day is ok, same as standard cron. An optional "TZ=Zone " prefix set the
+// location of schedule.
+func ParseCron(expr string) (Schedule, error) {
+	input := expr
+	offset := 0
+	var loc *time.Location
+	if strings.HasPrefix(expr, "TZ=") || strings.HasPrefix(expr, "CRON_TZ=") {
+		i := strings.IndexByte(expr, ' ')
+		if i < 0 {
+			return nil, &ParseError{Input: input, Pos: len(expr), Msg: "missing expression after time zone"}
+		}
+		name := expr[strings.IndexByte(expr, '=')+1 : i]
+		l, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, &ParseError{Input: input, Pos: 0, Msg: "unknown time zone " + name}
+		}
+		loc = l
+		offset = i + 1
+		expr = expr[i+1:]
+	}
+
+	if strings.HasPrefix(expr, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(expr[len("@every "):]))
+		if err != nil || d <= 0 {
+			return nil, &ParseError{Input: input, Pos: offset + len("@every "), Msg: "invalid duration"}
+		}
+		return Every(d), nil
+	}
+	if strings.HasPrefix(expr, "@") {
+		e, has := cronDescriptors[strings.TrimSpace(expr)]
+		if !has {
+			return nil, &ParseError{Input: input, Pos: offset, Msg: "unknown descriptor"}
+		}
+		s, _ := parseCronFields(input, 0, e)
+		s.Location = loc
+		return s, nil
+	}
+
+	s, err := parseCronFields(input, offset, expr)
+	if err != nil {
+		return nil, err
+	}
+	s.Location = loc
+	return s, nil
+}
+
+// MustParseCron is same as ParseCron but panic on error
+func MustParseCron(expr string) Schedule {
+	s, err := ParseCron(expr)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func parseCronFields(input string, offset int, expr string) (*CronSchedule, error) {
+	type part struct {
+		s   string
+		pos int
+	}
+	var parts []part
+	for i := 0; i < len(expr); {
+		for i < len(expr) && (expr[i] == ' ' || expr[i] == '\t') {
+			i++
+		}
+		begin := i
+		for i < len(expr) && expr[i] != ' ' && expr[i] != '\t' {
+			i++
+		}
+		if begin < i {
+			parts = append(parts, part{s: expr[begin:i], pos: offset + begin})
+		}
+	}
+
+	fields := []cronField{cronMinute, cronHour, cronDom, cronMonth, cronDow}
+	switch len(parts) {
+	case 5:
+		parts = append([]part{{s: "0"}}, parts...)
+	case 6:
+	default:
+		return nil, &ParseError{Input: input, Pos: offset, Msg: "expect 5 or 6 fields, got " + strconv.Itoa(len(parts))}
+	}
+	fields = append([]cronField{cronSecond}, fields...)
+
+	var bits [6]uint64
+	for i, f := range fields {
+		b, err := f.parse(input, parts[i].pos, parts[i].s)
+		if err != nil {
+			return nil, err
+		}
+		bits[i] = b
+	}
+	// sunday can be 0 or 7
+	if bits[5]&(1<<7) != 0 {
+		bits[5] = bits[5]&^(1<<7) | 1
+	}
+
+	return &CronSchedule{
+		second: bits[0],
+		minute: bits[1],
+		hour:   bits[2],
+		dom:    bits[3],
+		month:  bits[4],
+		dow:    bits[5],
+	}, nil
+}
+
+func (f cronField) parse(input string, pos int, s string) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(s, ",") {
+		b, err := f.parseItem(input, pos, item)
+		if err != nil {
+			return 0, err
+		}
+		bits |= b
+		pos += len(item) + 1
+	}
+	return bits, nil
+}
+
+func (f cronField) parseItem(input string, pos int, item string) (uint64, error) {
+	fail := func(offset int, msg string) (uint64, error) {
+		return 0, &ParseError{Input: input, Pos: pos + offset, Msg: f.name + ": " + msg}
+	}
+	if item == "" {
+		return fail(0, "empty value")
+	}
+
+	rng, step := item, ""
+	hasStep := false
+	if i := strings.IndexByte(item, '/'); i >= 0 {
+		rng, step, hasStep = item[:i], item[i+1:], true
+	}
+
+	var (
+		low, high uint
+		star      bool
+		err       error
+	)
+	switch {
+	case rng == "*" || rng == "?":
+		low, high, star = f.min, f.max, true
+		if f.max == 7 {
+			high = 6
+		}
+	case strings.IndexByte(rng, '-') > 0:
+		i := strings.IndexByte(rng, '-')
+		if low, err = f.value(rng[:i]); err != nil {
+			return fail(0, err.Error())
+		}
+		if high, err = f.value(rng[i+1:]); err != nil {
+			return fail(i+1, err.Error())
+		}
+		if low > high {
+			return fail(0, "range start is greater than end")
+		}
+	default:
+		if low, err = f.value(rng); err != nil {
+			return fail(0, err.Error())
+		}
+		high = low
+		if hasStep {
+			high = f.max
+		}
+	}
+
+	n := uint(1)
+	if hasStep {
+		v, err := strconv.ParseUint(step, 10, 8)
+		if err != nil || v == 0 {
+			return fail(len(rng)+1, "invalid step "+strconv.Quote(step))
+		}
+		n = uint(v)
+	}
+
+	var bits uint64
+	for v := low; v <= high; v += n {
+		bits |= 1 << v
+	}
+	if star && !hasStep {
+		bits |= starBit
+	}
+	return bits, nil
+}
+
+func (f cronField) value(s string) (uint, error) {
+	lower := strings.ToLower(s)
+	for i, name := range f.names {
+		if name != "" && name == lower {
+			return uint(i), nil
+		}
+	}
+
+	v, err := strconv.ParseUint(s, 10, 8)
+	if err != nil {
+		return 0, errors.Err("invalid value " + strconv.Quote(s))
+	}
+	if uint(v) < f.min || uint(v) > f.max {
+		return 0, errors.Err("value " + s + " out of range [" +
+			strconv.Itoa(int(f.min)) + ", " + strconv.Itoa(int(f.max)) + "]")
+	}
+	return uint(v), nil
+}
+
+func hasBit(bits uint64, v int) bool {
+	return bits&(1<<uint(v)) != 0
+}
+
+func (s *CronSchedule) dayMatches(t time.Time) bool {
+	domMatch := hasBit(s.dom, t.Day())
+	dowMatch := hasBit(s.dow, int(t.Weekday()))
+	if s.dom&starBit != 0 || s.dow&starBit != 0 {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// Next return the first matched time after t, it's returned in the location
+// of schedule. For daylight saving time, times skipped by the transition are
+// skipped, and times repeated by the transition match twice.
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	loc := s.Location
+	if loc == nil {
+		loc = Location
+	}
+
+	t = t.In(loc)
+	t = t.Add(time.Second - time.Duration(t.Nanosecond()))
+	yearLimit := t.Year() + 5
+	added := false
+
+wrap:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for !hasBit(s.month, int(t.Month())) {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 1, 0)
+		if t.Month() == time.January {
+			goto wrap
+		}
+	}
+
+	for !s.dayMatches(t) {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 0, 1)
+		// midnight may not exist because of daylight saving time
+		if h := t.Hour(); h != 0 {
+			if h > 12 {
+				t = t.Add(time.Duration(24-h) * time.Hour)
+			} else {
+				t = t.Add(-time.Duration(h) * time.Hour)
+			}
+		}
+		if t.Day() == 1 {
+			goto wrap
+		}
+	}
+
+	for !hasBit(s.hour, t.Hour()) {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+		}
+		t = t.Add(time.Hour)
+		if t.Hour() == 0 {
+			goto wrap
+		}
+	}
+
+	for !hasBit(s.minute, t.Minute()) {
+		if !added {
+			added = true
+			t = t.Truncate(time.Minute)
+		}
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto wrap
+		}
+	}
+
+	for !hasBit(s.second, t.Second()) {
+		if !added {
+			added = true
+			t = t.Truncate(time.Second)
+		}
+		t = t.Add(time.Second)
+		if t.Second() == 0 {
+			goto wrap
+		}
+	}
+
+	return t
+}
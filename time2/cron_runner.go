@@ -0,0 +1,274 @@
+package time2
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cosiner/gohper/runtime2"
+)
+
+// Overlap decide what to do when a job is due but it's previous run hasn't
+// finished
+type Overlap int
+
+const (
+	// OverlapSkip skip the run
+	OverlapSkip Overlap = iota
+	// OverlapQueue run it after previous runs finished
+	OverlapQueue
+	// OverlapAllow run it concurrently
+	OverlapAllow
+)
+
+// CronJob is a snapshot of job status
+type CronJob struct {
+	ID       int
+	Name     string
+	Schedule Schedule
+	Overlap  Overlap
+	// Next is the next run time, zero if the job won't run any more
+	Next time.Time
+	// Prev is the last run time
+	Prev    time.Time
+	Runs    int
+	Skipped int
+	Running int
+	Queued  int
+}
+
+type cronJob struct {
+	CronJob
+	fn func() error
+}
+
+// Cron run jobs by their schedules, the zero value is ready to use
+type Cron struct {
+	// Clock default RealClock
+	Clock Clock
+	// OnError is called when job return error or panic, panics are
+	// *runtime2.PanicError
+	OnError func(name string, err error)
+
+	mu      sync.Mutex
+	jobs    []*cronJob
+	nextID  int
+	running bool
+	wake    chan struct{}
+	stop    chan struct{}
+	loopWg  sync.WaitGroup
+	jobWg   sync.WaitGroup
+}
+
+func (c *Cron) clock() Clock {
+	if c.Clock == nil {
+		return RealClock
+	}
+	return c.Clock
+}
+
+// Add a job with cron expression, see ParseCron for the format
+func (c *Cron) Add(name, spec string, overlap Overlap, fn func() error) (int, error) {
+	s, err := ParseCron(spec)
+	if err != nil {
+		return 0, err
+	}
+
+	return c.AddSchedule(name, s, overlap, fn), nil
+}
+
+// AddSchedule add a job with schedule and return it's id
+func (c *Cron) AddSchedule(name string, s Schedule, overlap Overlap, fn func() error) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nextID++
+	j := &cronJob{
+		CronJob: CronJob{
+			ID:       c.nextID,
+			Name:     name,
+			Schedule: s,
+			Overlap:  overlap,
+			Next:     s.Next(c.clock().Now()),
+		},
+		fn: fn,
+	}
+	c.jobs = append(c.jobs, j)
+	c.notify()
+
+	return j.ID
+}
+
+// Remove job by id, running and queued runs are not affected
+func (c *Cron) Remove(id int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, j := range c.jobs {
+		if j.ID == id {
+			c.jobs = append(c.jobs[:i], c.jobs[i+1:]...)
+			c.notify()
+			return true
+		}
+	}
+	return false
+}
+
+// Jobs return snapshots of all jobs, sorted by next run time, jobs won't run
+// any more are at the end
+func (c *Cron) Jobs() []CronJob {
+	c.mu.Lock()
+	jobs := make([]CronJob, len(c.jobs))
+	for i, j := range c.jobs {
+		jobs[i] = j.CronJob
+	}
+	c.mu.Unlock()
+
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return before(jobs[i].Next, jobs[j].Next)
+	})
+	return jobs
+}
+
+// before compare times, zero time is considered as the latest
+func before(a, b time.Time) bool {
+	if a.IsZero() || b.IsZero() {
+		return !a.IsZero() && b.IsZero()
+	}
+	return a.Before(b)
+}
+
+// notify the loop job list changed, must be called with lock
+func (c *Cron) notify() {
+	if c.running {
+		select {
+		case c.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Start run jobs in background
+func (c *Cron) Start() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.running {
+		return
+	}
+
+	c.running = true
+	c.wake = make(chan struct{}, 1)
+	c.stop = make(chan struct{})
+	now := c.clock().Now()
+	for _, j := range c.jobs {
+		j.Next = j.Schedule.Next(now)
+	}
+
+	c.loopWg.Add(1)
+	go c.loop()
+}
+
+// Stop scheduling and wait running jobs to finish, queued runs are dropped
+func (c *Cron) Stop() {
+	c.mu.Lock()
+	if !c.running {
+		c.mu.Unlock()
+		return
+	}
+	c.running = false
+	close(c.stop)
+	c.mu.Unlock()
+
+	c.loopWg.Wait()
+	c.jobWg.Wait()
+}
+
+func (c *Cron) loop() {
+	defer c.loopWg.Done()
+
+	clock := c.clock()
+	for {
+		c.mu.Lock()
+		var next time.Time
+		for _, j := range c.jobs {
+			if before(j.Next, next) {
+				next = j.Next
+			}
+		}
+		c.mu.Unlock()
+
+		var timer Timer
+		var fire <-chan time.Time
+		if !next.IsZero() {
+			timer = clock.NewTimer(next.Sub(clock.Now()))
+			fire = timer.C()
+		}
+
+		select {
+		case <-fire:
+			c.runDue(clock.Now())
+		case <-c.wake:
+		case <-c.stop:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+
+		select {
+		case <-c.stop:
+			return
+		default:
+		}
+	}
+}
+
+func (c *Cron) runDue(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, j := range c.jobs {
+		if j.Next.IsZero() || j.Next.After(now) {
+			continue
+		}
+
+		j.Prev = j.Next
+		j.Next = j.Schedule.Next(now)
+		switch {
+		case j.Running == 0 || j.Overlap == OverlapAllow:
+			c.run(j)
+		case j.Overlap == OverlapQueue:
+			j.Queued++
+		default:
+			j.Skipped++
+		}
+	}
+}
+
+// run job in a new goroutine, must be called with lock
+func (c *Cron) run(j *cronJob) {
+	j.Running++
+	j.Runs++
+	c.jobWg.Add(1)
+
+	go func() {
+		defer c.jobWg.Done()
+
+		for {
+			err := runtime2.Call(j.fn)
+			if err != nil && c.OnError != nil {
+				c.OnError(j.Name, err)
+			}
+
+			c.mu.Lock()
+			if j.Queued == 0 || !c.running {
+				j.Running--
+				j.Queued = 0
+				c.mu.Unlock()
+				return
+			}
+			j.Queued--
+			j.Runs++
+			c.mu.Unlock()
+		}
+	}()
+}
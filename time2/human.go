@@ -0,0 +1,216 @@
+package time2
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const Week = 7 * Day
+
+type humanUnit struct {
+	names []string // first is the short name, second is the long name
+	unit  time.Duration
+}
+
+var humanUnits = []humanUnit{
+	{[]string{"w", "week", "weeks", "wk", "wks"}, Week},
+	{[]string{"d", "day", "days"}, Day},
+	{[]string{"h", "hour", "hours", "hr", "hrs"}, time.Hour},
+	{[]string{"m", "minute", "minutes", "min", "mins"}, time.Minute},
+	{[]string{"s", "second", "seconds", "sec", "secs"}, time.Second},
+	{[]string{"ms", "millisecond", "milliseconds", "msec", "msecs"}, time.Millisecond},
+	{[]string{"us", "microsecond", "microseconds", "µs", "usec", "usecs", "u"}, time.Microsecond},
+	{[]string{"ns", "nanosecond", "nanoseconds", "nsec", "nsecs", "n"}, time.Nanosecond},
+}
+
+func lookupHumanUnit(name string) (time.Duration, bool) {
+	name = strings.ToLower(name)
+	for _, u := range humanUnits {
+		for _, n := range u.names {
+			if n == name {
+				return u.unit, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// ParseDuration parse human readable duration, it's a superset of
+// time.ParseDuration, and the compact format of ParseHuman except that
+// "m" means minute:
+//
+//	"1d 2h30m", "1.5h", "-3w", "90 minutes", "2 days, 3 hours and 4 mins"
+//
+// Units are case-insensitive, supported units are w, d, h, m, s, ms, us, ns
+// and their long names, u and n of ParseHuman are also accepted. A sign is
+// only allowed at the beginning.
+func ParseDuration(s string) (time.Duration, error) {
+	p := durationParser{input: s}
+	return p.parse()
+}
+
+type durationParser struct {
+	input string
+	pos   int
+}
+
+func (p *durationParser) fail(pos int, msg string) (time.Duration, error) {
+	return 0, &ParseError{Input: p.input, Pos: pos, Msg: msg}
+}
+
+func (p *durationParser) skipSeparators() {
+	for p.pos < len(p.input) {
+		switch c := p.input[p.pos]; {
+		case c == ' ' || c == '\t' || c == ',':
+			p.pos++
+		case strings.HasPrefix(p.input[p.pos:], "and "):
+			p.pos += len("and ")
+		default:
+			return
+		}
+	}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func (p *durationParser) parse() (time.Duration, error) {
+	s := p.input
+	for p.pos < len(s) && s[p.pos] == ' ' {
+		p.pos++
+	}
+
+	neg := false
+	if p.pos < len(s) && (s[p.pos] == '-' || s[p.pos] == '+') {
+		neg = s[p.pos] == '-'
+		p.pos++
+	}
+	if p.pos == len(s) {
+		return p.fail(p.pos, "missing duration")
+	}
+
+	// accumulate absolute value, the limit of negative value is 1<<63
+	limit := uint64(math.MaxInt64)
+	if neg {
+		limit++
+	}
+	var (
+		total float64
+		d     uint64
+	)
+	for p.skipSeparators(); p.pos < len(s); p.skipSeparators() {
+		begin := p.pos
+		for p.pos < len(s) && isDigit(s[p.pos]) {
+			p.pos++
+		}
+		intEnd := p.pos
+		if p.pos < len(s) && s[p.pos] == '.' {
+			p.pos++
+			for p.pos < len(s) && isDigit(s[p.pos]) {
+				p.pos++
+			}
+		}
+		if p.pos == begin || (intEnd == begin && p.pos == begin+1) {
+			return p.fail(begin, "expect number")
+		}
+		num := s[begin:p.pos]
+
+		for p.pos < len(s) && s[p.pos] == ' ' {
+			p.pos++
+		}
+		unitBegin := p.pos
+		for p.pos < len(s) && !isDigit(s[p.pos]) && !strings.ContainsRune(" \t,.+-", rune(s[p.pos])) {
+			p.pos++
+		}
+		if unitBegin == p.pos {
+			if num == "0" && p.pos == len(s) && d == 0 {
+				return 0, nil
+			}
+			return p.fail(unitBegin, "missing unit")
+		}
+		unit, ok := lookupHumanUnit(s[unitBegin:p.pos])
+		if !ok {
+			return p.fail(unitBegin, "unknown unit "+strconv.Quote(s[unitBegin:p.pos]))
+		}
+
+		if !strings.Contains(num, ".") {
+			n, err := strconv.ParseUint(num, 10, 64)
+			if err != nil || n > limit/uint64(unit) || n*uint64(unit) > limit-d {
+				return p.fail(begin, "duration overflow")
+			}
+			d += n * uint64(unit)
+		} else {
+			f, _ := strconv.ParseFloat(num, 64)
+			total += f * float64(unit)
+		}
+	}
+
+	if total != 0 {
+		if total+float64(d) >= float64(limit) {
+			return p.fail(0, "duration overflow")
+		}
+		d += uint64(total + 0.5)
+	}
+	if neg {
+		return -time.Duration(d), nil
+	}
+	return time.Duration(d), nil
+}
+
+// FormatHuman format duration with days, hours, minutes, seconds and sub
+// second units, precision is the max number of units counted from the largest
+// non-zero one, zero units are counted but not shown, smaller units are
+// truncated, 0 means no limit, e.g. 2d0h5m with precision 2 is "2d". Long format looks like "2 days 3 hours",
+// short format looks like "2d3h".
+func FormatHuman(d time.Duration, precision int, long bool) string {
+	var buf []byte
+	u := uint64(d)
+	if d < 0 {
+		buf = append(buf, '-')
+		u = -u
+	}
+
+	n, units := 0, 0 // number of shown units and counted units
+	for _, hu := range humanUnits[1:] {
+		unit := uint64(hu.unit)
+		v := u / unit
+		if v == 0 {
+			if units > 0 {
+				if units++; units == precision {
+					break
+				}
+			}
+			continue
+		}
+		u -= v * unit
+		if long && n > 0 {
+			buf = append(buf, ' ')
+		}
+		buf = strconv.AppendUint(buf, v, 10)
+		if long {
+			buf = append(buf, ' ')
+			buf = append(buf, hu.names[1]...)
+			if v != 1 {
+				buf = append(buf, 's')
+			}
+		} else {
+			buf = append(buf, hu.names[0]...)
+		}
+
+		n++
+		if units++; units == precision {
+			break
+		}
+	}
+
+	if n == 0 {
+		if long {
+			return "0 seconds"
+		}
+		return "0s"
+	}
+	return string(buf)
+}
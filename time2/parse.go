@@ -0,0 +1,282 @@
+package time2
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultLayouts is the default layouts tried by DateParser in order
+var DefaultLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02 15:04:05",
+	"2006/01/02 15:04",
+	"2006/01/02",
+	"Jan 2 2006 15:04:05",
+	"Jan 2 2006 15:04",
+	"Jan 2 2006",
+	"Jan 2, 2006",
+	"January 2 2006",
+	"January 2, 2006",
+	"2 Jan 2006",
+	"2 January 2006",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC850,
+	time.RFC822Z,
+	time.RFC822,
+	time.UnixDate,
+	time.ANSIC,
+}
+
+var timeLayouts = []string{"15:04:05", "15:04", "3:04:05pm", "3:04pm", "3pm"}
+
+// DateParser parse date in multiple formats:
+//   - layouts in Layouts
+//   - unix seconds with optional fraction, such as "1420070400.5"
+//   - "now", "today", "yesterday", "tomorrow", weekday names, "next monday",
+//     "last friday", "next week|month|year", "last week|month|year", these day
+//     words can be followed by a time like "10:00", "10:30:15", "3pm", "at 9am"
+//   - a time only, such as "10:00", it means today
+//   - offsets like "in 3 days", "2 weeks ago", "in 1h30m", "1 year 2 months ago",
+//     years, months, weeks and days are calendar units, others are durations
+//
+// Relative dates are anchored to the time of Clock in Location.
+type DateParser struct {
+	// Layouts default DefaultLayouts
+	Layouts []string
+	// Location for dates without time zone, default package Location
+	Location *time.Location
+	// Clock default RealClock
+	Clock Clock
+}
+
+// ParseAny parse date with default DateParser
+func ParseAny(s string) (time.Time, error) {
+	var p DateParser
+	return p.Parse(s)
+}
+
+func (p *DateParser) location() *time.Location {
+	if p.Location != nil {
+		return p.Location
+	}
+	return Location
+}
+
+func (p *DateParser) now() time.Time {
+	clock := p.Clock
+	if clock == nil {
+		clock = RealClock
+	}
+	return clock.Now().In(p.location())
+}
+
+func (p *DateParser) Parse(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	loc := p.location()
+
+	layouts := p.Layouts
+	if layouts == nil {
+		layouts = DefaultLayouts
+	}
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+
+	if t, ok := parseUnix(s); ok {
+		return t.In(loc), nil
+	}
+
+	return p.parseRelative(s)
+}
+
+func parseUnix(s string) (time.Time, bool) {
+	sec, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		sec, frac = s[:i], s[i+1:]
+	}
+	if sec == "" || len(frac) > 9 || strings.TrimLeft(sec+frac, "0123456789") != "" {
+		return time.Time{}, false
+	}
+
+	n, err := strconv.ParseInt(sec, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	var nsec int64
+	if frac != "" {
+		nsec, _ = strconv.ParseInt((frac + "000000000")[:9], 10, 64)
+	}
+	return time.Unix(n, nsec), true
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+func (p *DateParser) parseRelative(input string) (time.Time, error) {
+	fail := func(pos int, msg string) (time.Time, error) {
+		return time.Time{}, &ParseError{Input: input, Pos: pos, Msg: msg}
+	}
+	if input == "" {
+		return fail(0, "empty date")
+	}
+
+	s := strings.ToLower(input)
+	now := p.now()
+	switch {
+	case s == "now":
+		return now, nil
+	case strings.HasPrefix(s, "in "):
+		return p.parseOffset(input, len("in "), now, 1)
+	case strings.HasSuffix(s, " ago"):
+		return p.parseOffset(input[:len(s)-len(" ago")], 0, now, -1)
+	}
+
+	// day word and optional time
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, p.location())
+	word, rest, pos := s, "", 0
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		word, rest, pos = s[:i], strings.TrimSpace(s[i+1:]), i+1
+	}
+
+	var day time.Time
+	switch word {
+	case "today":
+		day = today
+	case "yesterday":
+		day = today.AddDate(0, 0, -1)
+	case "tomorrow":
+		day = today.AddDate(0, 0, 1)
+	case "next", "last":
+		dir := 1
+		if word == "last" {
+			dir = -1
+		}
+		target, remain := rest, ""
+		if i := strings.IndexByte(rest, ' '); i >= 0 {
+			target, remain = rest[:i], strings.TrimSpace(rest[i+1:])
+		}
+		switch target {
+		case "week":
+			day = today.AddDate(0, 0, 7*dir)
+		case "month":
+			day = today.AddDate(0, dir, 0)
+		case "year":
+			day = today.AddDate(dir, 0, 0)
+		default:
+			wd, ok := weekdays[target]
+			if !ok {
+				return fail(pos, "unknown day "+strconv.Quote(target))
+			}
+			day = today.AddDate(0, 0, dir)
+			for day.Weekday() != wd {
+				day = day.AddDate(0, 0, dir)
+			}
+		}
+		pos += len(target) + 1
+		rest = remain
+	default:
+		if wd, ok := weekdays[word]; ok {
+			// the coming weekday, today if it's the day
+			day = today
+			for day.Weekday() != wd {
+				day = day.AddDate(0, 0, 1)
+			}
+		} else if _, err := parseClock(s); err == nil {
+			day, rest, pos = today, s, 0
+		} else {
+			return fail(0, "unrecognized date")
+		}
+	}
+
+	if strings.HasPrefix(rest, "at ") {
+		rest = strings.TrimSpace(rest[len("at "):])
+		pos += len("at ")
+	}
+	if rest == "" {
+		return day, nil
+	}
+	clock, err := parseClock(rest)
+	if err != nil {
+		return fail(pos, "invalid time "+strconv.Quote(rest))
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0, p.location()), nil
+}
+
+func parseClock(s string) (time.Time, error) {
+	var err error
+	for _, layout := range timeLayouts {
+		var t time.Time
+		if t, err = time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+var offsetRegexp = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*([a-zµ]+)`)
+
+// parseOffset parse offsets like "1 year 2 months 3 days 4h" and apply them to now
+func (p *DateParser) parseOffset(input string, begin int, now time.Time, sign int) (time.Time, error) {
+	s := strings.ToLower(input[begin:])
+	var (
+		years, months, days int
+		dur                 time.Duration
+		last                int
+	)
+	matches := offsetRegexp.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return time.Time{}, &ParseError{Input: input, Pos: begin, Msg: "expect offset"}
+	}
+	for _, m := range matches {
+		if gap := strings.TrimSpace(strings.Replace(s[last:m[0]], ",", "", -1)); gap != "" && gap != "and" {
+			return time.Time{}, &ParseError{Input: input, Pos: begin + last, Msg: "unexpected " + strconv.Quote(gap)}
+		}
+		last = m[1]
+
+		num, unit := s[m[2]:m[3]], s[m[4]:m[5]]
+		n, err := strconv.Atoi(num)
+		calendar := true
+		switch unit {
+		case "y", "yr", "yrs", "year", "years":
+			years += n
+		case "mo", "month", "months":
+			months += n
+		case "w", "wk", "wks", "week", "weeks":
+			days += 7 * n
+		case "d", "day", "days":
+			days += n
+		default:
+			calendar = false
+			d, perr := ParseDuration(num + unit)
+			if perr != nil {
+				return time.Time{}, &ParseError{Input: input, Pos: begin + m[4], Msg: "unknown unit " + strconv.Quote(unit)}
+			}
+			dur += d
+		}
+		if calendar && err != nil {
+			return time.Time{}, &ParseError{Input: input, Pos: begin + m[2], Msg: "expect integer for " + unit}
+		}
+	}
+	if tail := strings.TrimSpace(s[last:]); tail != "" {
+		return time.Time{}, &ParseError{Input: input, Pos: begin + last, Msg: "unexpected " + strconv.Quote(tail)}
+	}
+
+	return now.AddDate(sign*years, sign*months, sign*days).Add(time.Duration(sign) * dur), nil
+}
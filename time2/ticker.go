@@ -2,23 +2,36 @@ package time2
 
 import "time"
 
+// TimeTicker tick at first and every tick after that, it should be used as:
+//
+//	for {
+//		<-ticker.C()
+//	}
+//
+// ticks missed by slow receiver are dropped like time.Ticker.
 type TimeTicker struct {
-	timer  *time.Timer
-	ticker *time.Ticker
+	clock Clock
+	timer Timer
 
 	tm time.Time
 	tk time.Duration
 }
 
 func NewTimeTicker(first time.Time, tick time.Duration) *TimeTicker {
-	now := Now()
+	return NewTimeTickerClock(RealClock, first, tick)
+}
+
+// NewTimeTickerClock create a TimeTicker use the given clock
+func NewTimeTickerClock(clock Clock, first time.Time, tick time.Duration) *TimeTicker {
+	now := clock.Now()
 	sub := first.Sub(now)
 	for sub < 0 {
 		sub += tick
 	}
 
 	t := &TimeTicker{
-		timer: time.NewTimer(sub),
+		clock: clock,
+		timer: clock.NewTimer(sub),
 
 		tm: now.Add(sub),
 		tk: tick,
@@ -27,19 +40,26 @@ func NewTimeTicker(first time.Time, tick time.Duration) *TimeTicker {
 }
 
 func (t *TimeTicker) C() <-chan time.Time {
-	if Now().Before(t.tm) || len(t.timer.C) > 0 {
-		return t.timer.C
+	now := t.clock.Now()
+	if now.Before(t.tm) || len(t.timer.C()) > 0 {
+		return t.timer.C()
 	}
-	if t.ticker == nil {
-		t.ticker = time.NewTicker(t.tk)
+	if t.timer.Stop() {
+		// the tick is not received yet, restore it
+		t.timer.Reset(t.tm.Sub(now))
+		return t.timer.C()
 	}
-	return t.ticker.C
+
+	// last tick is received, schedule next one
+	due := t.tm.Add(t.tk)
+	for !due.Add(t.tk).After(now) {
+		due = due.Add(t.tk)
+	}
+	t.tm = due
+	t.timer.Reset(due.Sub(now))
+	return t.timer.C()
 }
 
 func (t *TimeTicker) Stop() {
-	if t.ticker == nil {
-		t.timer.Stop()
-	} else {
-		t.ticker.Stop()
-	}
+	t.timer.Stop()
 }
@@ -114,6 +114,8 @@ const (
 // 	'm': millsecond,
 // 	'u': microsecond,
 // 	'n': nanosecond
+//
+// It's kept for compatibility, ParseDuration support more formats.
 func ParseHuman(timestr string) (time.Duration, error) {
 	var t, counter time.Duration
 	for i, l := 0, len(timestr); i < l; i++ {
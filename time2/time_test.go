@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/cosiner/gohper/runtime2"
 	"github.com/cosiner/gohper/testing2"
 )
 
@@ -55,3 +56,381 @@ func TestTiming(t *testing.T) {
 	tt.Log(timing())
 	tt.Log(timing())
 }
+
+func TestFakeClock(t *testing.T) {
+	tt := testing2.Wrap(t)
+
+	start := time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFakeClock(start)
+	timer := c.NewTimer(time.Second)
+	ticker := c.NewTicker(300 * time.Millisecond)
+	tt.Eq(2, c.Waiters())
+
+	c.Add(999 * time.Millisecond)
+	tt.Eq(0, len(timer.C()))
+	tt.Eq(start.Add(300*time.Millisecond), <-ticker.C())
+	tt.Eq(0, len(ticker.C())) // ticks are dropped for slow receivers
+
+	c.Add(time.Millisecond)
+	tt.Eq(start.Add(time.Second), <-timer.C())
+	tt.False(timer.Stop())
+	tt.False(timer.Reset(time.Second))
+	tt.True(timer.Stop())
+	ticker.Stop()
+	tt.Eq(0, c.Waiters())
+
+	done := make(chan struct{})
+	go func() {
+		c.Sleep(time.Hour)
+		close(done)
+	}()
+	c.BlockUntil(1)
+	c.Add(time.Hour)
+	<-done
+	tt.Eq(time.Hour+time.Second, c.Since(start))
+
+	tt.Eq(c.Now(), <-c.After(0))
+}
+
+func TestTimeTickerClock(t *testing.T) {
+	tt := testing2.Wrap(t)
+
+	start := time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFakeClock(start)
+	ticker := NewTimeTickerClock(c, start.Add(-time.Minute+time.Second), time.Minute)
+
+	c.Add(time.Second)
+	tt.Eq(start.Add(time.Second), <-ticker.C())
+	c.Add(time.Minute)
+	tt.Eq(start.Add(time.Minute+time.Second), <-ticker.C())
+	c.Add(3 * time.Minute) // missed ticks are dropped
+	tt.Eq(start.Add(4*time.Minute+time.Second), <-ticker.C())
+	c.Add(time.Minute)
+	tt.Eq(start.Add(5*time.Minute+time.Second), <-ticker.C())
+	ticker.Stop()
+	tt.Eq(0, c.Waiters())
+}
+
+func TestParseCron(t *testing.T) {
+	tt := testing2.Wrap(t)
+
+	for _, expr := range []string{"* * * *", "60 * * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "* * * foo *", "@weekday", "@every x", "TZ=Nowhere/City * * * * *"} {
+		_, err := ParseCron(expr)
+		_, is := err.(*ParseError)
+		tt.True(is)
+	}
+	_, err := ParseCron("1 2 3 4,6,x 5")
+	tt.Eq(`time2: month: invalid value "x" at position 10 of "1 2 3 4,6,x 5"`, err.Error())
+
+	s, err := ParseCron("0 0 1 1 *")
+	tt.Nil(err)
+	tt.DeepEq(MustParseCron("@yearly"), s)
+	tt.DeepEq(MustParseCron("0 0 0 1 JAN ?"), s)
+	tt.DeepEq(MustParseCron("0 9 * * 7"), MustParseCron("0 9 * * sun"))
+}
+
+func TestCronNext(t *testing.T) {
+	tt := testing2.Wrap(t)
+
+	loc := time.UTC
+	base := time.Date(2015, 1, 31, 10, 30, 15, 500, loc)
+	date := func(month time.Month, day, hour, min, sec int) time.Time {
+		return time.Date(2015, month, day, hour, min, sec, 0, loc)
+	}
+	for _, c := range []struct {
+		expr string
+		next time.Time
+	}{
+		{"TZ=UTC * * * * *", date(1, 31, 10, 31, 0)},
+		{"TZ=UTC * * * * * *", date(1, 31, 10, 30, 16)},
+		{"TZ=UTC */20 * * * * *", date(1, 31, 10, 30, 20)},
+		{"TZ=UTC 0 9-17/4 * * mon-fri", date(2, 2, 9, 0, 0)},
+		{"TZ=UTC 0 9-17/4 * 1 *", date(1, 31, 13, 0, 0)},
+		{"TZ=UTC 0 0 30 * *", date(3, 30, 0, 0, 0)},
+		{"TZ=UTC 0 0 13 * fri", date(2, 6, 0, 0, 0)},
+		{"TZ=UTC @hourly", date(1, 31, 11, 0, 0)},
+		{"TZ=UTC @monthly", date(2, 1, 0, 0, 0)},
+		{"@every 90s", date(1, 31, 10, 31, 45)},
+		{"TZ=UTC 0 0 30 2 *", time.Time{}},
+	} {
+		next := MustParseCron(c.expr).Next(base)
+		if !next.Equal(c.next) {
+			t.Errorf("%s: expect %s, got %s", c.expr, c.next, next)
+		}
+	}
+
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	// 2015-03-08 02:00 -> 03:00, 2015-11-01 02:00 -> 01:00
+	s := MustParseCron("TZ=America/New_York 30 2 * * *")
+	next := s.Next(time.Date(2015, 3, 7, 12, 0, 0, 0, ny))
+	tt.True(next.Equal(time.Date(2015, 3, 7, 2, 30, 0, 0, ny).AddDate(0, 0, 2)))
+	tt.Eq(ny.String(), next.Location().String())
+
+	s = MustParseCron("TZ=America/New_York 30 1 * * *")
+	first := s.Next(time.Date(2015, 11, 1, 0, 0, 0, 0, ny))
+	second := s.Next(first)
+	tt.Eq(time.Hour, second.Sub(first))
+}
+
+func TestCron(t *testing.T) {
+	tt := testing2.Wrap(t)
+
+	clock := NewFakeClock(time.Date(2015, 1, 1, 0, 0, 30, 0, time.UTC))
+	c := &Cron{Clock: clock}
+
+	var (
+		runs    = make(chan string, 10)
+		release = make(chan struct{})
+		errs    = make(chan error, 10)
+	)
+	c.OnError = func(name string, err error) {
+		errs <- err
+	}
+	block := func(name string) func() error {
+		return func() error {
+			runs <- name
+			<-release
+			return nil
+		}
+	}
+	skipID, err := c.Add("skip", "* * * * *", OverlapSkip, block("skip"))
+	tt.Nil(err)
+	_, err = c.Add("queue", "* * * * *", OverlapQueue, block("queue"))
+	tt.Nil(err)
+	_, err = c.Add("bad", "@every 2m", OverlapSkip, func() error { panic("bad") })
+	tt.Nil(err)
+	_, err = c.Add("bad spec", "* *", OverlapSkip, nil)
+	tt.NNil(err)
+
+	c.Start()
+	for i := 0; i < 3; i++ {
+		clock.BlockUntil(1)
+		clock.Add(time.Minute)
+		if i == 0 {
+			<-runs
+			<-runs
+		}
+		tt.Eventually(func() bool {
+			return clock.Waiters() == 1
+		}, time.Second, time.Millisecond)
+	}
+	jobs := c.Jobs()
+	tt.Eq(3, len(jobs))
+	tt.Eq("skip", jobs[0].Name)
+	tt.Eq(2, jobs[0].Skipped)
+	tt.Eq(2, jobs[1].Queued)
+	tt.Eq("bad", jobs[2].Name)
+	tt.True(jobs[0].Next.Equal(time.Date(2015, 1, 1, 0, 4, 0, 0, time.UTC)))
+	tt.True(jobs[2].Next.Equal(time.Date(2015, 1, 1, 0, 4, 30, 0, time.UTC)))
+	_, is := (<-errs).(*runtime2.PanicError)
+	tt.True(is)
+
+	tt.True(c.Remove(skipID))
+	tt.False(c.Remove(skipID))
+	close(release)
+	tt.Eq("queue", <-runs)
+	tt.Eq("queue", <-runs)
+	c.Stop()
+	jobs = c.Jobs()
+	tt.Eq(2, len(jobs))
+	tt.Eq(3, jobs[0].Runs)
+	tt.Eq(0, jobs[0].Running)
+}
+
+func TestParseDuration(t *testing.T) {
+	testing2.
+		Expect(Day+2*time.Hour+30*time.Minute, nil).Arg("1d 2h30m").
+		Expect(90*time.Minute, nil).Arg("1.5h").
+		Expect(-3*Week, nil).Arg("-3w").
+		Expect(90*time.Minute, nil).Arg("90 minutes").
+		Expect(2*Day+3*time.Hour+4*time.Minute, nil).Arg(" 2 days, 3 Hours and 4 mins").
+		Expect(time.Hour+30*time.Minute+10*time.Second, nil).Arg("1H30M10S").
+		Expect(1500*time.Microsecond, nil).Arg("1ms500µs").
+		Expect(3*time.Microsecond+4*time.Nanosecond, nil).Arg("3u4n").
+		Expect(500*time.Millisecond, nil).Arg(".5s").
+		Expect(time.Duration(0), nil).Arg("0").
+		Run(t, ParseDuration)
+
+	for _, c := range []struct {
+		input string
+		pos   int
+	}{
+		{"", 0},
+		{"-", 1},
+		{"1h 30", 5},
+		{"1h 30x", 5},
+		{"1h -30m", 3},
+		{"h", 0},
+		{"100000000000w", 0},
+	} {
+		_, err := ParseDuration(c.input)
+		if pe, is := err.(*ParseError); !is || pe.Pos != c.pos {
+			t.Errorf("%q: expect error at %d, got %v", c.input, c.pos, err)
+		}
+	}
+	_, err := ParseDuration("1h 30x")
+	testing2.Eq(t, `time2: unknown unit "x" at position 5 of "1h 30x"`, err.Error())
+}
+
+func TestFormatHuman(t *testing.T) {
+	d := 2*Day + 3*time.Hour + 40*time.Minute + time.Millisecond
+	testing2.
+		Expect("2 days 3 hours").Arg(d, 2, true).
+		Expect("2d3h40m1ms").Arg(d, 0, false).
+		Expect("-1 hour 1 second").Arg(-time.Hour-time.Second, 0, true).
+		Expect("0s").Arg(time.Duration(0), 1, false).
+		Expect("1m30s").Arg(90*time.Second, 3, false).
+		Expect("2d").Arg(2*Day+5*time.Minute, 2, false).
+		Expect("2 days 5 minutes").Arg(2*Day+5*time.Minute, 3, true).
+		Run(t, FormatHuman)
+
+	for _, d := range []time.Duration{d, -d, time.Nanosecond, 1<<63 - 1, -1 << 63} {
+		for _, long := range []bool{true, false} {
+			got, err := ParseDuration(FormatHuman(d, 0, long))
+			testing2.Nil(t, err).Eq(d, got)
+		}
+	}
+}
+
+func TestDateParser(t *testing.T) {
+	tt := testing2.Wrap(t)
+	loc := time.FixedZone("T", 8*3600)
+	now := time.Date(2015, 3, 18, 15, 4, 5, 0, loc) // Wednesday
+	p := DateParser{Location: loc, Clock: NewFakeClock(now)}
+	day := func(y int, m time.Month, d, h, min int) time.Time {
+		return time.Date(y, m, d, h, min, 0, 0, loc)
+	}
+
+	for _, c := range []struct {
+		input  string
+		expect time.Time
+	}{
+		{"2015-01-02T03:04:05Z", time.Date(2015, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"2015-01-02", day(2015, 1, 2, 0, 0)},
+		{"2015/01/02 10:30", day(2015, 1, 2, 10, 30)},
+		{"Jan 2 2015", day(2015, 1, 2, 0, 0)},
+		{"January 2, 2015", day(2015, 1, 2, 0, 0)},
+		{"1420070400", time.Unix(1420070400, 0)},
+		{"1420070400.5", time.Unix(1420070400, 5e8)},
+		{"now", now},
+		{"today", day(2015, 3, 18, 0, 0)},
+		{"yesterday 10:00", day(2015, 3, 17, 10, 0)},
+		{"Tomorrow at 3pm", day(2015, 3, 19, 15, 0)},
+		{"friday", day(2015, 3, 20, 0, 0)},
+		{"wednesday", day(2015, 3, 18, 0, 0)},
+		{"next wednesday 9:30", day(2015, 3, 25, 9, 30)},
+		{"last monday", day(2015, 3, 16, 0, 0)},
+		{"next month", day(2015, 4, 18, 0, 0)},
+		{"8:15", day(2015, 3, 18, 8, 15)},
+		{"in 3 days", now.AddDate(0, 0, 3)},
+		{"in 1h30m", now.Add(90 * time.Minute)},
+		{"2 weeks ago", now.AddDate(0, 0, -14)},
+		{"1 year, 2 months and 3 days ago", now.AddDate(-1, -2, -3)},
+	} {
+		got, err := p.Parse(c.input)
+		if err != nil || !got.Equal(c.expect) {
+			t.Errorf("%q: expect %v, got %v, %v", c.input, c.expect, got, err)
+		}
+	}
+
+	for _, c := range []struct {
+		input string
+		pos   int
+	}{
+		{"", 0},
+		{"someday", 0},
+		{"next decade", 5},
+		{"today at noon", 9},
+		{"in 3 days later", 9},
+		{"in 3 fortnights", 5},
+		{"1.5 days ago", 0},
+	} {
+		_, err := p.Parse(c.input)
+		if pe, is := err.(*ParseError); !is || pe.Pos != c.pos {
+			t.Errorf("%q: expect error at %d, got %v", c.input, c.pos, err)
+		}
+	}
+
+	p.Layouts = []string{"02/01/2006"}
+	got, err := p.Parse("02/01/2015")
+	tt.Nil(err)
+	tt.True(got.Equal(day(2015, 1, 2, 0, 0)))
+}
+
+func TestLocalDate(t *testing.T) {
+	tt := testing2.Wrap(t)
+	d := NewLocalDate(2016, 1, 31)
+	tt.Eq("2016-02-29", d.AddMonths(1).String())
+	tt.Eq("2015-11-30", d.AddMonths(-2).String())
+	tt.Eq("2017-02-28", NewLocalDate(2016, 2, 29).AddYears(1).String())
+	tt.Eq("2016-03-02", NewLocalDate(2016, 2, 31).String())
+	tt.Eq("2015-12-31", d.AddDays(-31).String())
+	tt.Eq(366, NewLocalDate(2017, 1, 1).DaysSince(NewLocalDate(2016, 1, 1)))
+	tt.True(d.Before(d.AddDays(1)))
+	tt.False(LocalDate{2015, 2, 29}.IsValid())
+
+	p, err := ParseLocalDate("2016-01-31")
+	tt.Nil(err)
+	tt.Eq(d, p)
+	tt.NNil(p.UnmarshalText([]byte("2016-13-01")))
+	tt.Eq(d, p)
+
+	y, w := NewLocalDate(2016, 1, 1).ISOWeek()
+	tt.Eq(2015, y)
+	tt.Eq(53, w)
+	tt.Eq(NewLocalDate(2015, 12, 28), ISOWeekStart(2015, 53))
+	tt.Eq(NewLocalDate(2018, 12, 31), ISOWeekStart(2019, 1))
+	tt.Eq(53, ISOWeeksInYear(2015))
+	tt.Eq(52, ISOWeeksInYear(2016))
+}
+
+func TestCalendarBounds(t *testing.T) {
+	tt := testing2.Wrap(t)
+	loc := time.FixedZone("T", 8*3600)
+	tm := time.Date(2016, 8, 17, 15, 4, 5, 6, loc) // Wednesday
+	at := func(m time.Month, d int) time.Time {
+		return time.Date(2016, m, d, 0, 0, 0, 0, loc)
+	}
+	tt.True(StartOfDay(tm).Equal(at(8, 17)))
+	tt.True(EndOfDay(tm).Equal(at(8, 18).Add(-1)))
+	tt.True(StartOfWeek(tm, time.Monday).Equal(at(8, 15)))
+	tt.True(StartOfWeek(tm, time.Sunday).Equal(at(8, 14)))
+	tt.True(StartOfWeek(tm, time.Thursday).Equal(at(8, 11)))
+	tt.True(EndOfWeek(tm, time.Monday).Equal(at(8, 22).Add(-1)))
+	tt.True(StartOfMonth(tm).Equal(at(8, 1)))
+	tt.True(EndOfMonth(tm).Equal(at(9, 1).Add(-1)))
+	tt.Eq(3, Quarter(tm))
+	tt.True(StartOfQuarter(tm).Equal(at(7, 1)))
+	tt.True(EndOfQuarter(tm).Equal(at(10, 1).Add(-1)))
+	tt.True(EndOfQuarter(time.Date(2016, 12, 1, 0, 0, 0, 0, loc)).Equal(time.Date(2017, 1, 1, 0, 0, 0, 0, loc).Add(-1)))
+	tt.True(AddMonths(time.Date(2016, 3, 31, 10, 0, 0, 0, loc), -1).Equal(time.Date(2016, 2, 29, 10, 0, 0, 0, loc)))
+}
+
+func TestBusinessCalendar(t *testing.T) {
+	tt := testing2.Wrap(t)
+	c := NewBusinessCalendar(
+		Annual(time.December, 25),
+		NewHolidayList(NewLocalDate(2015, 12, 28)),
+	)
+	fri := NewLocalDate(2015, 12, 18)
+	tt.True(c.IsBusinessDay(fri))
+	tt.False(c.IsBusinessDay(fri.AddDays(1)))
+	tt.False(c.IsBusinessDay(NewLocalDate(2015, 12, 25)))
+
+	tt.Eq(NewLocalDate(2015, 12, 21), c.AddBusinessDays(fri, 1))
+	// 21 22 23 24 29
+	tt.Eq(NewLocalDate(2015, 12, 29), c.AddBusinessDays(fri, 5))
+	tt.Eq(fri, c.AddBusinessDays(NewLocalDate(2015, 12, 29), -5))
+	tt.Eq(NewLocalDate(2015, 12, 19), c.AddBusinessDays(NewLocalDate(2015, 12, 19), 0))
+	tt.Eq(5, c.BusinessDays(NewLocalDate(2015, 12, 21), NewLocalDate(2015, 12, 30)))
+	tt.Eq(-5, c.BusinessDays(NewLocalDate(2015, 12, 30), NewLocalDate(2015, 12, 21)))
+	tt.Eq(NewLocalDate(2015, 12, 29), c.NextBusinessDay(NewLocalDate(2015, 12, 25)))
+	tt.Eq(NewLocalDate(2015, 12, 24), c.PrevBusinessDay(NewLocalDate(2015, 12, 28)))
+
+	c.Weekend = []time.Weekday{time.Friday}
+	tt.True(c.IsBusinessDay(fri.AddDays(1)))
+	tt.False(c.IsBusinessDay(fri))
+}
@@ -2,19 +2,13 @@
 package encodeio
 
 import (
-	"bufio"
-	"bytes"
-	"io"
+	"io/ioutil"
 	"os"
 
 	"github.com/cosiner/gohper/encoding"
 	"github.com/cosiner/gohper/os2/file"
 )
 
-var (
-	commentPrefix = []byte("//")
-)
-
 func Read(fname string, v interface{}, codec encoding.Codec) error {
 	return file.Read(fname, func(fd *os.File) error {
 		return codec.Decode(fd, v)
@@ -25,27 +19,13 @@ func ReadJSON(fname string, v interface{}) error {
 	return Read(fname, v, encoding.JSON)
 }
 
+// ReadJSONWithComment read JSON file with line/block comments and trailing commas
 func ReadJSONWithComment(fname string, v interface{}) error {
-	return file.Read(fname, func(fd *os.File) error {
-		buf := bytes.NewBuffer(make([]byte, 0, 1024))
-		br := bufio.NewReader(fd)
-
-		for {
-			line, _, err := br.ReadLine()
-			if err == io.EOF {
-				if len(line) == 0 {
-					break
-				}
-			} else if err != nil {
-				return err
-			}
-
-			if !bytes.HasPrefix(bytes.TrimSpace(line), commentPrefix) {
-				buf.Write(line)
-			}
-		}
-		return encoding.JSON.Unmarshal(buf.Bytes(), v)
-	})
+	return Read(fname, v, JSONC)
+}
+
+func ReadJSON5(fname string, v interface{}) error {
+	return Read(fname, v, JSON5)
 }
 
 func Write(fname string, v interface{}, codec encoding.Codec) error {
@@ -59,3 +39,41 @@ func Trunc(fname string, v interface{}, codec encoding.Codec) error {
 		return codec.Encode(fd, v)
 	})
 }
+
+// AtomicWrite replace file content with encoded value atomically
+func AtomicWrite(fname string, v interface{}, codec encoding.Codec) error {
+	return file.AtomicWrite(fname, func(fd *os.File) error {
+		return codec.Encode(fd, v)
+	})
+}
+
+// WriteJSONWithComment write v to file as indented JSON, comments in the
+// original file are kept for the keys/elements still exist
+func WriteJSONWithComment(fname string, v interface{}, indent string) error {
+	data, err := encoding.JSON.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	n, err := ParseJSONC(data)
+	if err != nil {
+		return err
+	}
+
+	orig, err := ioutil.ReadFile(fname)
+	if err == nil {
+		var o *Node
+		if o, err = ParseJSON5(orig); err != nil {
+			return err
+		}
+		MergeComments(n, o)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	return file.AtomicWrite(fname, func(fd *os.File) error {
+		_, err := fd.Write(n.Format(indent))
+
+		return err
+	})
+}
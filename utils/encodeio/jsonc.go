@@ -0,0 +1,754 @@
+package encodeio
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// SyntaxError is returned when parsing a malformed document, Line and Column
+// start from 1, Column count in characters
+type SyntaxError struct {
+	Line, Column int
+	Msg          string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Msg)
+}
+
+type Kind uint8
+
+const (
+	KindNull Kind = iota
+	KindBool
+	KindNumber
+	KindString
+	KindArray
+	KindObject
+)
+
+type Member struct {
+	Key   string
+	Value *Node
+}
+
+// Node is a value of JSON document with it's comments, comments are stored
+// in their raw form include the "//" or "/* */" delimiters
+type Node struct {
+	Kind Kind
+	// Value is "true" or "false" for KindBool, the number normalized to JSON
+	// form for KindNumber, and the unescaped content for KindString
+	Value   string
+	Elems   []*Node
+	Members []Member
+
+	// Comments is the comments before the node, for object member, it's the
+	// comments before the key
+	Comments []string
+	// LineComment is the comment follows the node on the same line
+	LineComment string
+	// InnerComments is the comments before the closing bracket of array/object
+	InnerComments []string
+	// Foot is the comments after the document root, only the root has it
+	Foot []string
+}
+
+// ParseJSONC parse JSON with line/block comments and trailing commas
+func ParseJSONC(data []byte) (*Node, error) {
+	return parse(data, false)
+}
+
+// ParseJSON5 parse JSON5 document, it's a superset of JSONC, also allow
+// single quoted strings, unquoted keys, hexadecimal numbers, leading/trailing
+// decimal point, explicit plus sign, Infinity, NaN and multi-line strings
+func ParseJSON5(data []byte) (*Node, error) {
+	return parse(data, true)
+}
+
+func parse(data []byte, json5 bool) (*Node, error) {
+	p := parser{
+		s: scanner{
+			data:  data,
+			line:  1,
+			col:   1,
+			json5: json5,
+		},
+	}
+
+	n, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+
+	tok, err := p.peek()
+	if err != nil {
+		return nil, err
+	}
+	n.LineComment = p.lineComment()
+	if tok.kind != tokEOF {
+		return nil, p.unexpected(tok, "end of input")
+	}
+	n.Foot = p.comments()
+
+	return n, nil
+}
+
+type tokenKind uint8
+
+const (
+	tokEOF tokenKind = iota
+	tokDelim
+	tokString
+	tokNumber
+	tokIdent
+	tokComment
+)
+
+type token struct {
+	kind  tokenKind
+	delim byte
+	val   string
+
+	line, col int
+	// newline report whether there is a line break between previous token
+	// and this one
+	newline bool
+}
+
+func (t token) is(delim byte) bool {
+	return t.kind == tokDelim && t.delim == delim
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of input"
+	case tokDelim:
+		return fmt.Sprintf("'%c'", t.delim)
+	case tokString:
+		return "string"
+	case tokNumber:
+		return "number " + t.val
+	case tokIdent:
+		return "identifier " + t.val
+	default:
+		return "comment"
+	}
+}
+
+type scanner struct {
+	data      []byte
+	pos       int
+	line, col int
+	json5     bool
+}
+
+func (s *scanner) errorf(line, col int, format string, args ...interface{}) error {
+	return &SyntaxError{
+		Line:   line,
+		Column: col,
+		Msg:    fmt.Sprintf(format, args...),
+	}
+}
+
+// advance move forward n bytes, column only increase at the leading byte
+// of utf8 characters
+func (s *scanner) advance(n int) {
+	for ; n > 0 && s.pos < len(s.data); n-- {
+		c := s.data[s.pos]
+		s.pos++
+		if c == '\n' {
+			s.line++
+			s.col = 1
+		} else if c&0xC0 != 0x80 {
+			s.col++
+		}
+	}
+}
+
+func (s *scanner) skipSpace() (newline bool) {
+	for s.pos < len(s.data) {
+		switch c := s.data[s.pos]; c {
+		case ' ', '\t', '\r':
+			s.advance(1)
+		case '\n':
+			newline = true
+			s.advance(1)
+		case '\v', '\f':
+			if !s.json5 {
+				return
+			}
+			s.advance(1)
+		default:
+			if c < utf8.RuneSelf {
+				return
+			}
+
+			r, n := utf8.DecodeRune(s.data[s.pos:])
+			switch {
+			case r == 0xFEFF && (s.pos == 0 || s.json5):
+			case s.json5 && (r == 0x2028 || r == 0x2029):
+				newline = true
+			case s.json5 && unicode.Is(unicode.Zs, r):
+			default:
+				return
+			}
+			s.advance(n)
+		}
+	}
+
+	return
+}
+
+func (s *scanner) next() (token, error) {
+	newline := s.skipSpace()
+	tok := token{
+		line:    s.line,
+		col:     s.col,
+		newline: newline,
+	}
+	if s.pos >= len(s.data) {
+		return tok, nil
+	}
+
+	switch c := s.data[s.pos]; {
+	case c == '{' || c == '}' || c == '[' || c == ']' || c == ':' || c == ',':
+		s.advance(1)
+		tok.kind = tokDelim
+		tok.delim = c
+		return tok, nil
+	case c == '/':
+		return s.comment(tok)
+	case c == '"' || (c == '\'' && s.json5):
+		return s.str(tok, c)
+	case c == '-' || (c >= '0' && c <= '9') || (s.json5 && (c == '+' || c == '.')):
+		return s.number(tok)
+	default:
+		return s.ident(tok)
+	}
+}
+
+func (s *scanner) comment(tok token) (token, error) {
+	start := s.pos
+	if start+1 < len(s.data) {
+		switch s.data[start+1] {
+		case '/':
+			end := bytes.IndexByte(s.data[start:], '\n')
+			if end < 0 {
+				end = len(s.data) - start
+			}
+			s.advance(end)
+
+			tok.kind = tokComment
+			tok.val = strings.TrimRight(string(s.data[start:s.pos]), "\r")
+			return tok, nil
+		case '*':
+			end := bytes.Index(s.data[start+2:], []byte("*/"))
+			if end < 0 {
+				return tok, s.errorf(tok.line, tok.col, "unterminated block comment")
+			}
+			s.advance(end + 4)
+
+			tok.kind = tokComment
+			tok.val = string(s.data[start:s.pos])
+			return tok, nil
+		}
+	}
+
+	return tok, s.errorf(tok.line, tok.col, "unexpected character '/'")
+}
+
+func (s *scanner) str(tok token, quote byte) (token, error) {
+	s.advance(1)
+
+	var buf []byte
+	for {
+		if s.pos >= len(s.data) {
+			return tok, s.errorf(tok.line, tok.col, "unterminated string")
+		}
+
+		switch c := s.data[s.pos]; {
+		case c == quote:
+			s.advance(1)
+			tok.kind = tokString
+			tok.val = string(buf)
+			return tok, nil
+		case c == '\\':
+			var err error
+			if buf, err = s.escape(buf); err != nil {
+				return tok, err
+			}
+		case c < 0x20:
+			return tok, s.errorf(s.line, s.col, "invalid character %q in string", c)
+		default:
+			buf = append(buf, c)
+			s.advance(1)
+		}
+	}
+}
+
+func (s *scanner) escape(buf []byte) ([]byte, error) {
+	line, col := s.line, s.col
+	if s.pos+1 >= len(s.data) {
+		return buf, s.errorf(line, col, "unterminated string")
+	}
+
+	r, n := utf8.DecodeRune(s.data[s.pos+1:])
+	s.advance(1 + n)
+	switch r {
+	case '"', '\\', '/':
+		return append(buf, byte(r)), nil
+	case 'b':
+		return append(buf, '\b'), nil
+	case 'f':
+		return append(buf, '\f'), nil
+	case 'n':
+		return append(buf, '\n'), nil
+	case 'r':
+		return append(buf, '\r'), nil
+	case 't':
+		return append(buf, '\t'), nil
+	case 'u':
+		r, ok := s.hex(4)
+		if !ok {
+			return buf, s.errorf(line, col, "invalid unicode escape")
+		}
+		if utf16.IsSurrogate(r) {
+			r2 := unicode.ReplacementChar
+			if s.pos+1 < len(s.data) && s.data[s.pos] == '\\' && s.data[s.pos+1] == 'u' {
+				pos, l, c := s.pos, s.line, s.col
+				s.advance(2)
+				if r2, ok = s.hex(4); !ok {
+					return buf, s.errorf(l, c, "invalid unicode escape")
+				}
+				if r2 = utf16.DecodeRune(r, r2); r2 == unicode.ReplacementChar {
+					s.pos, s.line, s.col = pos, l, c
+				}
+			}
+			r = r2
+		}
+		return appendRune(buf, r), nil
+	}
+
+	if !s.json5 {
+		return buf, s.errorf(line, col, "invalid escape character %q", r)
+	}
+
+	switch r {
+	case 'v':
+		return append(buf, '\v'), nil
+	case '0':
+		if s.pos < len(s.data) && s.data[s.pos] >= '0' && s.data[s.pos] <= '9' {
+			return buf, s.errorf(line, col, "octal escape is not allowed")
+		}
+		return append(buf, 0), nil
+	case 'x':
+		r, ok := s.hex(2)
+		if !ok {
+			return buf, s.errorf(line, col, "invalid hex escape")
+		}
+		return appendRune(buf, r), nil
+	case '\r':
+		if s.pos < len(s.data) && s.data[s.pos] == '\n' {
+			s.advance(1)
+		}
+		return buf, nil
+	case '\n', 0x2028, 0x2029:
+		return buf, nil
+	case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return buf, s.errorf(line, col, "invalid escape character %q", r)
+	}
+
+	return appendRune(buf, r), nil
+}
+
+func (s *scanner) hex(n int) (rune, bool) {
+	if s.pos+n > len(s.data) {
+		return 0, false
+	}
+
+	var r rune
+	for i := 0; i < n; i++ {
+		c := s.data[s.pos+i]
+		switch {
+		case c >= '0' && c <= '9':
+			c -= '0'
+		case c >= 'a' && c <= 'f':
+			c -= 'a' - 10
+		case c >= 'A' && c <= 'F':
+			c -= 'A' - 10
+		default:
+			return 0, false
+		}
+		r = r<<4 | rune(c)
+	}
+	s.advance(n)
+
+	return r, true
+}
+
+func appendRune(buf []byte, r rune) []byte {
+	var b [utf8.UTFMax]byte
+	n := utf8.EncodeRune(b[:], r)
+
+	return append(buf, b[:n]...)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// number scan a number and normalize it to JSON form, Infinity and NaN are
+// kept as is
+func (s *scanner) number(tok token) (token, error) {
+	var (
+		d   = s.data
+		i   = s.pos
+		neg bool
+	)
+	if d[i] == '-' || d[i] == '+' {
+		neg = d[i] == '-'
+		i++
+	}
+
+	sign := ""
+	if neg {
+		sign = "-"
+	}
+	tok.kind = tokNumber
+
+	if s.json5 && i < len(d) && (d[i] == 'I' || d[i] == 'N') {
+		s.advance(i - s.pos)
+		id, err := s.ident(tok)
+		if err != nil {
+			return tok, err
+		}
+
+		switch id.val {
+		case "Infinity":
+			tok.val = sign + id.val
+		case "NaN":
+			tok.val = id.val
+		default:
+			return tok, s.errorf(tok.line, tok.col, "invalid number")
+		}
+		return tok, nil
+	}
+
+	if s.json5 && i+1 < len(d) && d[i] == '0' && (d[i+1] == 'x' || d[i+1] == 'X') {
+		i += 2
+		start := i
+		for i < len(d) && isHexDigit(d[i]) {
+			i++
+		}
+		if i == start {
+			return tok, s.errorf(tok.line, tok.col, "invalid hexadecimal number")
+		}
+
+		n, _ := new(big.Int).SetString(string(d[start:i]), 16)
+		tok.val = sign + n.String()
+		s.advance(i - s.pos)
+		return tok, nil
+	}
+
+	intStart := i
+	for i < len(d) && isDigit(d[i]) {
+		i++
+	}
+	intPart := string(d[intStart:i])
+	if len(intPart) > 1 && intPart[0] == '0' {
+		return tok, s.errorf(tok.line, tok.col, "leading zero in number")
+	}
+
+	var (
+		fracPart string
+		hasDot   bool
+	)
+	if i < len(d) && d[i] == '.' {
+		hasDot = true
+		i++
+		start := i
+		for i < len(d) && isDigit(d[i]) {
+			i++
+		}
+		fracPart = string(d[start:i])
+	}
+	if (intPart == "" && (!s.json5 || fracPart == "")) || (hasDot && fracPart == "" && !s.json5) {
+		return tok, s.errorf(tok.line, tok.col, "invalid number")
+	}
+
+	var expPart string
+	if i < len(d) && (d[i] == 'e' || d[i] == 'E') {
+		start := i
+		i++
+		if i < len(d) && (d[i] == '+' || d[i] == '-') {
+			i++
+		}
+		digits := i
+		for i < len(d) && isDigit(d[i]) {
+			i++
+		}
+		if i == digits {
+			return tok, s.errorf(tok.line, tok.col, "invalid number exponent")
+		}
+		expPart = string(d[start:i])
+	}
+
+	if intPart == "" {
+		intPart = "0"
+	}
+	if fracPart != "" {
+		fracPart = "." + fracPart
+	}
+	tok.val = sign + intPart + fracPart + expPart
+	s.advance(i - s.pos)
+
+	return tok, nil
+}
+
+func (s *scanner) ident(tok token) (token, error) {
+	start := s.pos
+	for s.pos < len(s.data) {
+		r, n := utf8.DecodeRune(s.data[s.pos:])
+		if r == '_' || r == '$' || unicode.IsLetter(r) ||
+			(s.pos > start && (unicode.IsDigit(r) || unicode.In(r, unicode.Mn, unicode.Mc, unicode.Pc))) {
+			s.advance(n)
+		} else {
+			break
+		}
+	}
+	if s.pos == start {
+		r, _ := utf8.DecodeRune(s.data[s.pos:])
+		return tok, s.errorf(tok.line, tok.col, "unexpected character %q", r)
+	}
+
+	tok.kind = tokIdent
+	tok.val = string(s.data[start:s.pos])
+	return tok, nil
+}
+
+// maxDepth is the max nesting depth of arrays and objects
+const maxDepth = 10000
+
+type parser struct {
+	s       scanner
+	tok     token
+	peeked  bool
+	pending []token
+	depth   int
+}
+
+// peek return next non-comment token, comments before it are stored to pending
+func (p *parser) peek() (token, error) {
+	for !p.peeked {
+		tok, err := p.s.next()
+		if err != nil {
+			return tok, err
+		}
+
+		if tok.kind == tokComment {
+			p.pending = append(p.pending, tok)
+		} else {
+			p.tok, p.peeked = tok, true
+		}
+	}
+
+	return p.tok, nil
+}
+
+func (p *parser) next() (token, error) {
+	tok, err := p.peek()
+	p.peeked = false
+
+	return tok, err
+}
+
+func (p *parser) comments() []string {
+	if len(p.pending) == 0 {
+		return nil
+	}
+
+	comments := make([]string, len(p.pending))
+	for i, c := range p.pending {
+		comments[i] = c.val
+	}
+	p.pending = p.pending[:0]
+
+	return comments
+}
+
+// lineComment take the first pending comment if it's on the same line of
+// previous token
+func (p *parser) lineComment() string {
+	if len(p.pending) == 0 || p.pending[0].newline {
+		return ""
+	}
+
+	c := p.pending[0].val
+	p.pending = p.pending[1:]
+
+	return c
+}
+
+func (p *parser) unexpected(tok token, expect string) error {
+	return p.s.errorf(tok.line, tok.col, "unexpected %s, expecting %s", tok, expect)
+}
+
+func (p *parser) value() (*Node, error) {
+	tok, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	n := &Node{
+		Comments: p.comments(),
+	}
+	switch tok.kind {
+	case tokString:
+		n.Kind = KindString
+		n.Value = tok.val
+	case tokNumber:
+		n.Kind = KindNumber
+		n.Value = tok.val
+	case tokIdent:
+		switch tok.val {
+		case "true", "false":
+			n.Kind = KindBool
+			n.Value = tok.val
+		case "null":
+			n.Kind = KindNull
+		case "Infinity", "NaN":
+			if !p.s.json5 {
+				return nil, p.unexpected(tok, "value")
+			}
+			n.Kind = KindNumber
+			n.Value = tok.val
+		default:
+			return nil, p.unexpected(tok, "value")
+		}
+	case tokDelim:
+		if (tok.delim == '{' || tok.delim == '[') && p.depth >= maxDepth {
+			return nil, p.s.errorf(tok.line, tok.col, "exceeded max nesting depth %d", maxDepth)
+		}
+
+		p.depth++
+		switch tok.delim {
+		case '{':
+			err = p.object(n)
+		case '[':
+			err = p.array(n)
+		default:
+			err = p.unexpected(tok, "value")
+		}
+		p.depth--
+	default:
+		err = p.unexpected(tok, "value")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
+
+func (p *parser) object(n *Node) error {
+	n.Kind = KindObject
+	for {
+		tok, err := p.next()
+		if err != nil {
+			return err
+		}
+		if tok.is('}') {
+			n.InnerComments = p.comments()
+			return nil
+		}
+
+		comments := p.comments()
+		var key string
+		if tok.kind == tokString || (tok.kind == tokIdent && p.s.json5) {
+			key = tok.val
+		} else {
+			return p.unexpected(tok, "object key")
+		}
+
+		if tok, err = p.next(); err != nil {
+			return err
+		}
+		if !tok.is(':') {
+			return p.unexpected(tok, "':'")
+		}
+
+		val, err := p.value()
+		if err != nil {
+			return err
+		}
+		val.Comments = append(comments, val.Comments...)
+		n.Members = append(n.Members, Member{
+			Key:   key,
+			Value: val,
+		})
+
+		if err = p.separator(val, '}'); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *parser) array(n *Node) error {
+	n.Kind = KindArray
+	for {
+		tok, err := p.peek()
+		if err != nil {
+			return err
+		}
+		if tok.is(']') {
+			p.next()
+			n.InnerComments = p.comments()
+			return nil
+		}
+
+		val, err := p.value()
+		if err != nil {
+			return err
+		}
+		n.Elems = append(n.Elems, val)
+
+		if err = p.separator(val, ']'); err != nil {
+			return err
+		}
+	}
+}
+
+// separator consume the ',' after a value and attach the line comment to the
+// value, trailing comma before the closing bracket is allowed
+func (p *parser) separator(n *Node, end byte) error {
+	tok, err := p.peek()
+	if err != nil {
+		return err
+	}
+
+	if tok.is(',') {
+		p.next()
+		if _, err = p.peek(); err != nil {
+			return err
+		}
+		n.LineComment = p.lineComment()
+		return nil
+	}
+
+	n.LineComment = p.lineComment()
+	if !tok.is(end) {
+		return p.unexpected(tok, fmt.Sprintf("',' or '%c'", end))
+	}
+
+	return nil
+}
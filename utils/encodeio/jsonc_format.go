@@ -0,0 +1,253 @@
+package encodeio
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+
+	"github.com/cosiner/gohper/encoding"
+	"github.com/cosiner/gohper/errors"
+)
+
+const ErrNonJSONNumber = errors.Err("Infinity and NaN can't be represented in JSON")
+
+// Jsonc is a encoding.Codec, it decode JSON with comments and trailing commas,
+// or JSON5 if JSON5 is true, encode always output standard JSON
+type Jsonc struct {
+	JSON5 bool
+}
+
+var (
+	JSONC encoding.Codec = Jsonc{}
+	JSON5 encoding.Codec = Jsonc{JSON5: true}
+)
+
+func (Jsonc) Encode(w io.Writer, v interface{}) error {
+	return encoding.JSON.Encode(w, v)
+}
+
+func (Jsonc) Marshal(v interface{}) ([]byte, error) {
+	return encoding.JSON.Marshal(v)
+}
+
+func (c Jsonc) Decode(r io.Reader, v interface{}) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	return c.Unmarshal(data, v)
+}
+
+func (c Jsonc) Unmarshal(data []byte, v interface{}) error {
+	n, err := parse(data, c.JSON5)
+	if err != nil {
+		return err
+	}
+
+	data, err = n.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	return encoding.JSON.Unmarshal(data, v)
+}
+
+// MarshalJSON convert node to standard JSON without comments
+func (n *Node) MarshalJSON() ([]byte, error) {
+	buf := bytes.NewBuffer(make([]byte, 0, 1024))
+	err := n.marshal(buf)
+
+	return buf.Bytes(), err
+}
+
+func (n *Node) marshal(buf *bytes.Buffer) error {
+	switch n.Kind {
+	case KindNull:
+		buf.WriteString("null")
+	case KindBool:
+		buf.WriteString(n.Value)
+	case KindNumber:
+		if !isJSONNumber(n.Value) {
+			return ErrNonJSONNumber
+		}
+		buf.WriteString(n.Value)
+	case KindString:
+		writeQuote(buf, n.Value)
+	case KindArray:
+		buf.WriteByte('[')
+		for i, e := range n.Elems {
+			if i != 0 {
+				buf.WriteByte(',')
+			}
+			if err := e.marshal(buf); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case KindObject:
+		buf.WriteByte('{')
+		for i, m := range n.Members {
+			if i != 0 {
+				buf.WriteByte(',')
+			}
+			writeQuote(buf, m.Key)
+			buf.WriteByte(':')
+			if err := m.Value.marshal(buf); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	}
+
+	return nil
+}
+
+func isJSONNumber(s string) bool {
+	return s != "NaN" && s != "Infinity" && s != "-Infinity"
+}
+
+func writeQuote(buf *bytes.Buffer, s string) {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	buf.Truncate(buf.Len() - 1) // remove the newline added by Encode
+}
+
+// Format render node as indented JSON, comments are kept
+func (n *Node) Format(indent string) []byte {
+	f := formatter{
+		buf:    bytes.NewBuffer(make([]byte, 0, 1024)),
+		indent: indent,
+	}
+
+	f.comments(n.Comments, 0)
+	f.value(n, 0)
+	f.lineComment(n)
+	f.buf.WriteByte('\n')
+	f.comments(n.Foot, 0)
+
+	return f.buf.Bytes()
+}
+
+type formatter struct {
+	buf    *bytes.Buffer
+	indent string
+}
+
+func (f formatter) writeIndent(depth int) {
+	for i := 0; i < depth; i++ {
+		f.buf.WriteString(f.indent)
+	}
+}
+
+func (f formatter) comments(comments []string, depth int) {
+	for _, c := range comments {
+		f.writeIndent(depth)
+		f.buf.WriteString(c)
+		f.buf.WriteByte('\n')
+	}
+}
+
+func (f formatter) lineComment(n *Node) {
+	if n.LineComment != "" {
+		f.buf.WriteByte(' ')
+		f.buf.WriteString(n.LineComment)
+	}
+}
+
+// value write node start at current position, the comments before the node
+// and it's line comment is written by the caller
+func (f formatter) value(n *Node, depth int) {
+	switch n.Kind {
+	case KindArray:
+		if len(n.Elems) == 0 && len(n.InnerComments) == 0 {
+			f.buf.WriteString("[]")
+			return
+		}
+
+		f.buf.WriteString("[\n")
+		for i, e := range n.Elems {
+			f.comments(e.Comments, depth+1)
+			f.writeIndent(depth + 1)
+			f.value(e, depth+1)
+			if i != len(n.Elems)-1 {
+				f.buf.WriteByte(',')
+			}
+			f.lineComment(e)
+			f.buf.WriteByte('\n')
+		}
+		f.comments(n.InnerComments, depth+1)
+		f.writeIndent(depth)
+		f.buf.WriteByte(']')
+	case KindObject:
+		if len(n.Members) == 0 && len(n.InnerComments) == 0 {
+			f.buf.WriteString("{}")
+			return
+		}
+
+		f.buf.WriteString("{\n")
+		for i, m := range n.Members {
+			f.comments(m.Value.Comments, depth+1)
+			f.writeIndent(depth + 1)
+			writeQuote(f.buf, m.Key)
+			f.buf.WriteString(": ")
+			f.value(m.Value, depth+1)
+			if i != len(n.Members)-1 {
+				f.buf.WriteByte(',')
+			}
+			f.lineComment(m.Value)
+			f.buf.WriteByte('\n')
+		}
+		f.comments(n.InnerComments, depth+1)
+		f.writeIndent(depth)
+		f.buf.WriteByte('}')
+	case KindString:
+		writeQuote(f.buf, n.Value)
+	case KindNull:
+		f.buf.WriteString("null")
+	default:
+		f.buf.WriteString(n.Value)
+	}
+}
+
+// MergeComments copy comments of src to the node at same position of dst if
+// the dst node has no comments, object members are matched by key, array
+// elements are matched by index
+func MergeComments(dst, src *Node) {
+	if dst == nil || src == nil {
+		return
+	}
+
+	if len(dst.Comments) == 0 {
+		dst.Comments = src.Comments
+	}
+	if dst.LineComment == "" {
+		dst.LineComment = src.LineComment
+	}
+	if len(dst.Foot) == 0 {
+		dst.Foot = src.Foot
+	}
+	if dst.Kind != src.Kind {
+		return
+	}
+	if len(dst.InnerComments) == 0 {
+		dst.InnerComments = src.InnerComments
+	}
+
+	switch dst.Kind {
+	case KindArray:
+		for i := 0; i < len(dst.Elems) && i < len(src.Elems); i++ {
+			MergeComments(dst.Elems[i], src.Elems[i])
+		}
+	case KindObject:
+		members := make(map[string]*Node, len(src.Members))
+		for _, m := range src.Members {
+			members[m.Key] = m.Value
+		}
+		for _, m := range dst.Members {
+			MergeComments(m.Value, members[m.Key])
+		}
+	}
+}
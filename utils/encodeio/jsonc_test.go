@@ -0,0 +1,162 @@
+package encodeio
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cosiner/gohper/testing2"
+)
+
+type config struct {
+	Name  string   `json:"name"`
+	Port  int      `json:"port"`
+	Hosts []string `json:"hosts"`
+}
+
+func TestJSONC(t *testing.T) {
+	tt := testing2.Wrap(t)
+
+	data := `// header
+{
+	/* block
+	   comment */
+	"name": "a // b", // trailing
+	"port": 80,
+	"hosts": [
+		"a",
+		"b", // last
+	],
+}
+`
+	var c config
+	tt.Nil(JSONC.Unmarshal([]byte(data), &c))
+	tt.DeepEq(config{Name: "a // b", Port: 80, Hosts: []string{"a", "b"}}, c)
+
+	n, err := ParseJSONC([]byte(data))
+	tt.Nil(err)
+	tt.DeepEq([]string{"// header"}, n.Comments)
+	tt.Eq("// trailing", n.Members[0].Value.LineComment)
+	tt.Eq("// last", n.Members[2].Value.Elems[1].LineComment)
+
+	_, err = ParseJSONC([]byte("{\n  'a': 1\n}"))
+	e, is := err.(*SyntaxError)
+	tt.True(is)
+	tt.Eq(2, e.Line).Eq(3, e.Column)
+
+	_, err = ParseJSONC([]byte(`{"a": 1 /* unterminated`))
+	tt.NNil(err)
+	_, err = ParseJSONC([]byte(`[1,,2]`))
+	tt.NNil(err)
+	_, err = ParseJSONC([]byte(`{"a": 01}`))
+	tt.NNil(err)
+
+	deep := strings.Repeat("[", maxDepth) + strings.Repeat("]", maxDepth)
+	_, err = ParseJSONC([]byte(deep))
+	tt.Nil(err)
+	_, err = ParseJSONC([]byte("[" + deep + "]"))
+	e, is = err.(*SyntaxError)
+	tt.True(is)
+	tt.Eq(1, e.Line).Eq(maxDepth+1, e.Column)
+}
+
+func TestJSON5(t *testing.T) {
+	tt := testing2.Wrap(t)
+
+	data := `{
+	unquoted: 'single "quoted"',
+	hex: 0xFF,
+	half: .5,
+	int: 5.,
+	positive: +1,
+	line: 'a\
+b',
+	escape: '\x41é\'',
+}`
+	var v map[string]interface{}
+	tt.Nil(JSON5.Unmarshal([]byte(data), &v))
+	tt.DeepEq(map[string]interface{}{
+		"unquoted": `single "quoted"`,
+		"hex":      float64(255),
+		"half":     0.5,
+		"int":      float64(5),
+		"positive": float64(1),
+		"line":     "ab",
+		"escape":   "Aé'",
+	}, v)
+
+	tt.NNil(JSONC.Unmarshal([]byte(data), &v))
+
+	n, err := ParseJSON5([]byte(`[Infinity, -Infinity, NaN]`))
+	tt.Nil(err)
+	tt.Eq("-Infinity", n.Elems[1].Value)
+	_, err = n.MarshalJSON()
+	tt.Eq(ErrNonJSONNumber, err)
+}
+
+func TestFormat(t *testing.T) {
+	tt := testing2.Wrap(t)
+
+	data := `// header
+{
+	// name of server
+	"name": "srv", // inline
+	"empty": {},
+	"list": [
+		1, /* one */
+		2
+	]
+	// end
+}
+// footer
+`
+	n, err := ParseJSONC([]byte(data))
+	tt.Nil(err)
+	tt.Eq(`// header
+{
+	// name of server
+	"name": "srv", // inline
+	"empty": {},
+	"list": [
+		1, /* one */
+		2
+	]
+	// end
+}
+// footer
+`, string(n.Format("\t")))
+}
+
+func TestWriteJSONWithComment(t *testing.T) {
+	tt := testing2.Wrap(t)
+
+	dir, err := os.MkdirTemp("", "encodeio")
+	tt.Nil(err)
+	defer os.RemoveAll(dir)
+
+	fname := filepath.Join(dir, "config.json")
+	tt.Nil(os.WriteFile(fname, []byte(`{
+  // server name
+  "name": "a",
+  "port": 80, // http
+}`), 0644))
+
+	var c config
+	tt.Nil(ReadJSONWithComment(fname, &c))
+	c.Port = 8080
+	c.Hosts = []string{"h"}
+	tt.Nil(WriteJSONWithComment(fname, c, "  "))
+
+	data, err := os.ReadFile(fname)
+	tt.Nil(err)
+	tt.Eq(`{
+  // server name
+  "name": "a",
+  "port": 8080, // http
+  "hosts": [
+    "h"
+  ]
+}
+`, string(data))
+}
@@ -1,7 +1,11 @@
 // Package httperrs provide error types help interactive over http
 package httperrs
 
-import "github.com/cosiner/gohper/errors"
+import (
+	"net/http"
+
+	"github.com/cosiner/gohper/errors"
+)
 
 type Error interface {
 	error
@@ -17,8 +21,33 @@ func (e HTTPError) Code() int {
 	return e.code
 }
 
+func (e HTTPError) Unwrap() error {
+	return e.error
+}
+
 type Code int
 
+const (
+	BadRequest       Code = http.StatusBadRequest
+	Unauthorized     Code = http.StatusUnauthorized
+	Forbidden        Code = http.StatusForbidden
+	NotFound         Code = http.StatusNotFound
+	MethodNotAllowed Code = http.StatusMethodNotAllowed
+	NotAcceptable    Code = http.StatusNotAcceptable
+	Conflict         Code = http.StatusConflict
+	Gone             Code = http.StatusGone
+	TooLarge         Code = http.StatusRequestEntityTooLarge
+	Unsupported      Code = http.StatusUnsupportedMediaType
+	Unprocessable    Code = http.StatusUnprocessableEntity
+	TooManyRequests  Code = http.StatusTooManyRequests
+
+	Server             Code = http.StatusInternalServerError
+	NotImplemented     Code = http.StatusNotImplemented
+	BadGateway         Code = http.StatusBadGateway
+	ServiceUnavailable Code = http.StatusServiceUnavailable
+	GatewayTimeout     Code = http.StatusGatewayTimeout
+)
+
 func (c Code) New(err error) Error {
 	if err == nil {
 		return nil
@@ -41,6 +70,13 @@ func (c Code) NewS(err string) Error {
 	}
 }
 
+func (c Code) Newf(format string, v ...interface{}) Error {
+	return HTTPError{
+		error: errors.Newf(format, v...),
+		code:  int(c),
+	}
+}
+
 func Must(err error) Error {
 	if err == nil {
 		return nil
@@ -1,8 +1,13 @@
 package httperrs
 
 import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/cosiner/gohper/errors"
 	"github.com/cosiner/gohper/testing2"
 )
 
@@ -21,3 +26,91 @@ func TestErrors(t *testing.T) {
 	_ = NewS("err", 400)
 	_ = New(err, 400)
 }
+
+func TestMiddleware(t *testing.T) {
+	tt := testing2.Wrap(t)
+
+	var logged []error
+	m := &Middleware{
+		NewRequestID: func() string { return "rid" },
+		Logger: func(_ *http.Request, err error) {
+			logged = append(logged, err)
+		},
+	}
+	h := m.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		switch r.URL.Path {
+		case "/notfound":
+			return NotFound.New(errors.NewError("user_not_found", "no such user"))
+		case "/internal":
+			return errors.Err("database password is wrong")
+		case "/panic":
+			panic("boom")
+		}
+
+		w.Write([]byte(RequestID(r)))
+		return nil
+	})
+
+	serve := func(path, accept string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		if accept != "" {
+			r.Header.Set("Accept", accept)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve("/ok", "")
+	tt.Eq(200, w.Code)
+	tt.Eq("rid", w.Body.String())
+	tt.Eq("rid", w.Header().Get(DefRequestIDHeader))
+
+	w = serve("/notfound", "")
+	tt.Eq(404, w.Code)
+	tt.Eq(MIMEProblem, w.Header().Get("Content-Type"))
+	var p Problem
+	tt.Nil(json.Unmarshal(w.Body.Bytes(), &p))
+	tt.Eq(Problem{
+		Type:      "about:blank",
+		Title:     "Not Found",
+		Status:    404,
+		Detail:    "user_not_found: no such user",
+		Code:      "user_not_found",
+		RequestID: "rid",
+	}, p)
+
+	w = serve("/internal", "text/plain, application/json;q=0.5")
+	tt.Eq(500, w.Code)
+	tt.Eq("internal_server_error: Internal Server Error\n", w.Body.String())
+	tt.Eq(1, len(logged))
+
+	w = serve("/panic", "*/*")
+	tt.Eq(500, w.Code)
+	tt.Eq(MIMEProblem, w.Header().Get("Content-Type"))
+	tt.Eq(2, len(logged))
+
+	tt.Eq("too_many_requests", StatusCode(429))
+}
+
+func TestMiddlewareHijack(t *testing.T) {
+	tt := testing2.Wrap(t)
+	m := &Middleware{}
+	s := httptest.NewServer(m.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tt.Nil(http.NewResponseController(w).EnableFullDuplex())
+		tt.NNil(w.(http.Pusher).Push("/a", nil))
+		c, rw, err := w.(http.Hijacker).Hijack()
+		tt.Nil(err)
+		defer c.Close()
+		rw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\n\r\nhijacked")
+		rw.Flush()
+	})))
+	defer s.Close()
+
+	resp, err := http.Get(s.URL)
+	tt.Nil(err)
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	tt.Nil(err)
+	tt.Eq("hijacked", string(body))
+}
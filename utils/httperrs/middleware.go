@@ -0,0 +1,292 @@
+package httperrs
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cosiner/gohper/errors"
+	"github.com/cosiner/gohper/runtime2"
+)
+
+// HandlerFunc is a http handler that return error, the error is written to
+// client by Middleware
+type HandlerFunc func(http.ResponseWriter, *http.Request) error
+
+// Problem is the response body in RFC 7807 problem+json format
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+const (
+	MIMEProblem = "application/problem+json"
+	MIMEText    = "text/plain; charset=utf-8"
+
+	DefRequestIDHeader = "X-Request-Id"
+)
+
+// Middleware convert errors returned by HandlerFunc and recovered panics to
+// responses, client choose response format by the Accept header, default
+// is problem+json
+type Middleware struct {
+	// RequestIDHeader is the header to read request id from and write it
+	// back to, default DefRequestIDHeader
+	RequestIDHeader string
+	// NewRequestID generate request id if the request doesn't carry one,
+	// default generate 16 random bytes in hex
+	NewRequestID func() string
+	// ShowInternal show messages of 5xx errors to client, it's hidden by
+	// default to avoid leaking internal details
+	ShowInternal bool
+	// Logger is called for 5xx errors and panics
+	Logger func(r *http.Request, err error)
+	// StackSize is the buffer size of panic stack passed to Logger, default 4096
+	StackSize int
+}
+
+type ctxKey struct{}
+
+// RequestID return the request id set by Middleware
+func RequestID(r *http.Request) string {
+	id, _ := r.Context().Value(ctxKey{}).(string)
+
+	return id
+}
+
+func randomID() string {
+	var b [16]byte
+	rand.Read(b[:])
+
+	return hex.EncodeToString(b[:])
+}
+
+// Handle convert fn to http.Handler
+func (m *Middleware) Handle(fn HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w, r = m.prepare(w, r)
+		defer m.recover(w, r)
+
+		if err := fn(w, r); err != nil {
+			m.WriteError(w, r, err)
+		}
+	})
+}
+
+// Wrap recover panics of a normal http.Handler
+func (m *Middleware) Wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w, r = m.prepare(w, r)
+		defer m.recover(w, r)
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+func (m *Middleware) prepare(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, *http.Request) {
+	header := m.RequestIDHeader
+	if header == "" {
+		header = DefRequestIDHeader
+	}
+
+	id := r.Header.Get(header)
+	if id == "" {
+		if m.NewRequestID != nil {
+			id = m.NewRequestID()
+		} else {
+			id = randomID()
+		}
+	}
+	w.Header().Set(header, id)
+
+	return &responseWriter{ResponseWriter: w}, r.WithContext(context.WithValue(r.Context(), ctxKey{}, id))
+}
+
+func (m *Middleware) recover(w http.ResponseWriter, r *http.Request) {
+	e := recover()
+	if e == nil {
+		return
+	}
+	if e == http.ErrAbortHandler {
+		panic(e)
+	}
+
+	err, is := e.(error)
+	if !is {
+		err = errors.New(e)
+	}
+
+	if m.Logger != nil {
+		size := m.StackSize
+		if size <= 0 {
+			size = 4096
+		}
+		m.Logger(r, fmt.Errorf("panic: %v\n%s", e, runtime2.Stack(size, false)))
+	}
+	m.write(w, r, int(Server), errors.Code(err), err.Error())
+}
+
+// WriteError write error to client, if err is not an Error, it's treated as
+// an internal server error
+func (m *Middleware) WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	status := int(Server)
+	var he Error
+	if errors.As(err, &he) {
+		status = he.Code()
+		err = he
+	}
+
+	if status >= 500 && m.Logger != nil {
+		m.Logger(r, err)
+	}
+	m.write(w, r, status, errors.Code(err), err.Error())
+}
+
+func (m *Middleware) write(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	if rw, is := w.(*responseWriter); is && rw.wroteHeader {
+		return // response already started, nothing can do
+	}
+
+	title := http.StatusText(status)
+	if status >= 500 && !m.ShowInternal {
+		detail = title
+	}
+	if code == "" {
+		code = StatusCode(status)
+	}
+
+	p := Problem{
+		Type:      "about:blank",
+		Title:     title,
+		Status:    status,
+		Detail:    detail,
+		Code:      code,
+		RequestID: RequestID(r),
+	}
+
+	if preferText(r.Header.Get("Accept")) {
+		w.Header().Set("Content-Type", MIMEText)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(status)
+		fmt.Fprintf(w, "%s: %s\n", p.Code, p.Detail)
+		return
+	}
+
+	data, _ := json.Marshal(p)
+	w.Header().Set("Content-Type", MIMEProblem)
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// StatusCode convert status to a stable error code, such as "not_found"
+func StatusCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "status_" + strconv.Itoa(status)
+	}
+
+	text = strings.ToLower(strings.Replace(text, "-", " ", -1))
+	return strings.Join(strings.Fields(text), "_")
+}
+
+// preferText report whether client prefer plain text than json by the
+// Accept header, json is preferred if both are accepted with same quality
+func preferText(accept string) bool {
+	if accept == "" {
+		return false
+	}
+
+	var qJSON, qText float64 = -1, -1
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mime := strings.ToLower(strings.TrimSpace(params[0]))
+		q := 1.0
+		for _, p := range params[1:] {
+			p = strings.TrimSpace(p)
+			if strings.HasPrefix(p, "q=") {
+				if v, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+
+		switch mime {
+		case MIMEProblem, "application/json", "application/*":
+			if q > qJSON {
+				qJSON = q
+			}
+		case "text/plain", "text/html", "text/*":
+			if q > qText {
+				qText = q
+			}
+		case "*/*":
+			if q > qJSON {
+				qJSON = q
+			}
+			if q > qText {
+				qText = q
+			}
+		}
+	}
+
+	return qText > qJSON
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(data []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseWriter) Flush() {
+	if f, is := w.ResponseWriter.(http.Flusher); is {
+		w.wroteHeader = true
+		f.Flush()
+	}
+}
+
+// Hijack let handlers take over the connection, such as websocket, errors
+// are not written after hijacked
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, is := w.ResponseWriter.(http.Hijacker)
+	if !is {
+		return nil, nil, http.ErrNotSupported
+	}
+	c, rw, err := h.Hijack()
+	if err == nil {
+		w.wroteHeader = true
+	}
+	return c, rw, err
+}
+
+func (w *responseWriter) Push(target string, opts *http.PushOptions) error {
+	if p, is := w.ResponseWriter.(http.Pusher); is {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Unwrap return the original writer for http.ResponseController
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
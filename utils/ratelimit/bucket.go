@@ -0,0 +1,237 @@
+// Package ratelimit implements token bucket and sliding window rate limiters
+// and a keyed limiter set for per-client limiting.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/cosiner/gohper/errors"
+	"github.com/cosiner/gohper/time2"
+)
+
+const (
+	ErrExceedsBurst    = errors.Err("ratelimit: n exceeds burst")
+	ErrExceedsDeadline = errors.Err("ratelimit: wait would exceed context deadline")
+)
+
+// Limiter is the common interface of TokenBucket and SlidingWindow
+type Limiter interface {
+	Allow() bool
+	AllowN(n int) bool
+}
+
+// TokenBucket is a token bucket limiter, tokens are added at rate per second
+// until burst, each event consumes tokens.
+type TokenBucket struct {
+	mu     sync.Mutex
+	clock  time2.Clock
+	rate   float64
+	burst  int
+	tokens float64
+	last   time.Time
+	// lastEvent is the time of latest reservation
+	lastEvent time.Time
+}
+
+// NewTokenBucket create a full bucket, rate is tokens per second, if it's
+// +Inf all events are allowed
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	return NewTokenBucketClock(time2.RealClock, rate, burst)
+}
+
+func NewTokenBucketClock(clock time2.Clock, rate float64, burst int) *TokenBucket {
+	return &TokenBucket{
+		clock:  clock,
+		rate:   rate,
+		burst:  burst,
+		tokens: float64(burst),
+		last:   clock.Now(),
+	}
+}
+
+// Every convert interval between events to rate
+func Every(interval time.Duration) float64 {
+	if interval <= 0 {
+		return math.Inf(1)
+	}
+	return float64(time.Second) / float64(interval)
+}
+
+func (b *TokenBucket) Rate() float64 {
+	return b.rate
+}
+
+func (b *TokenBucket) Burst() int {
+	return b.burst
+}
+
+// Tokens return current available tokens, it's negative if there are
+// reservations waiting
+func (b *TokenBucket) Tokens() float64 {
+	b.mu.Lock()
+	b.advance(b.clock.Now())
+	tokens := b.tokens
+	b.mu.Unlock()
+	return tokens
+}
+
+func (b *TokenBucket) advance(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * b.rate
+		if max := float64(b.burst); b.tokens > max {
+			b.tokens = max
+		}
+		b.last = now
+	}
+}
+
+func (b *TokenBucket) Allow() bool {
+	return b.AllowN(1)
+}
+
+// AllowN report whether n events may happen now, tokens are consumed only
+// if it's allowed
+func (b *TokenBucket) AllowN(n int) bool {
+	if math.IsInf(b.rate, 1) {
+		return true
+	}
+	b.mu.Lock()
+	b.advance(b.clock.Now())
+	ok := b.tokens >= float64(n)
+	if ok {
+		b.tokens -= float64(n)
+	}
+	b.mu.Unlock()
+	return ok
+}
+
+// Reservation is tokens reserved from a TokenBucket, the event should wait
+// Delay before happening
+type Reservation struct {
+	bucket   *TokenBucket
+	ok       bool
+	tokens   int
+	at       time.Time
+	canceled bool
+}
+
+// OK report whether the tokens was reserved, it's false if n exceeds burst
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// Delay return the duration should wait before the event
+func (r *Reservation) Delay() time.Duration {
+	if !r.ok {
+		return time.Duration(math.MaxInt64)
+	}
+	if d := r.at.Sub(r.bucket.clock.Now()); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// Cancel give back reserved tokens if the reservation is not due, tokens
+// reserved by later reservations after it are not given back since they
+// won't be rescheduled
+func (r *Reservation) Cancel() {
+	if !r.ok || r.tokens == 0 {
+		return
+	}
+	b := r.bucket
+	b.mu.Lock()
+	now := b.clock.Now()
+	if !r.canceled && now.Before(r.at) {
+		r.canceled = true
+		b.advance(now)
+		restore := float64(r.tokens) - b.lastEvent.Sub(r.at).Seconds()*b.rate
+		if restore > 0 {
+			b.tokens += restore
+			if max := float64(b.burst); b.tokens > max {
+				b.tokens = max
+			}
+			if r.at.Equal(b.lastEvent) {
+				prev := r.at.Add(-durationOf(r.tokens, b.rate))
+				if !prev.Before(now) {
+					b.lastEvent = prev
+				}
+			}
+		}
+	}
+	b.mu.Unlock()
+}
+
+// durationOf return the duration to accumulate tokens
+func durationOf(tokens int, rate float64) time.Duration {
+	return time.Duration(float64(tokens) / rate * float64(time.Second))
+}
+
+func (b *TokenBucket) Reserve() *Reservation {
+	return b.ReserveN(1)
+}
+
+// ReserveN reserve n tokens, the reservation is not OK if n exceeds burst
+func (b *TokenBucket) ReserveN(n int) *Reservation {
+	now := b.clock.Now()
+	r := &Reservation{bucket: b, at: now}
+	if math.IsInf(b.rate, 1) {
+		r.ok = true
+		return r
+	}
+	if n > b.burst {
+		return r
+	}
+
+	b.mu.Lock()
+	b.advance(now)
+	if b.rate <= 0 && b.tokens < float64(n) {
+		// never refilled
+		b.mu.Unlock()
+		return r
+	}
+	b.tokens -= float64(n)
+	if b.tokens < 0 {
+		r.at = now.Add(time.Duration(-b.tokens / b.rate * float64(time.Second)))
+	}
+	b.lastEvent = r.at
+	b.mu.Unlock()
+	r.ok, r.tokens = true, n
+	return r
+}
+
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	return b.WaitN(ctx, 1)
+}
+
+// WaitN block until n events are allowed or context is done, it return
+// ErrExceedsDeadline immediately if the wait would exceed context deadline
+func (b *TokenBucket) WaitN(ctx context.Context, n int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r := b.ReserveN(n)
+	if !r.ok {
+		return ErrExceedsBurst
+	}
+	delay := r.Delay()
+	if delay == 0 {
+		return nil
+	}
+	if deadline, has := ctx.Deadline(); has && r.at.After(deadline) {
+		r.Cancel()
+		return ErrExceedsDeadline
+	}
+
+	timer := b.clock.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,125 @@
+package ratelimit
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cosiner/gohper/net2/http2"
+	"github.com/cosiner/gohper/time2"
+)
+
+type keyedEntry struct {
+	limiter Limiter
+	used    time.Time
+}
+
+// Keyed is a set of limiters for each key such as client ip, limiters are
+// created on demand and evicted after idle for a duration.
+type Keyed struct {
+	mu      sync.Mutex
+	clock   time2.Clock
+	idle    time.Duration
+	new     func() Limiter
+	entries map[string]*keyedEntry
+	swept   time.Time
+}
+
+// NewKeyed create a limiter set, new is used to create limiter for new keys,
+// idle limiters are evicted when accessing others, if idle is 0, they are
+// never evicted automatically.
+func NewKeyed(idle time.Duration, new func() Limiter) *Keyed {
+	return NewKeyedClock(time2.RealClock, idle, new)
+}
+
+func NewKeyedClock(clock time2.Clock, idle time.Duration, new func() Limiter) *Keyed {
+	return &Keyed{
+		clock:   clock,
+		idle:    idle,
+		new:     new,
+		entries: make(map[string]*keyedEntry),
+		swept:   clock.Now(),
+	}
+}
+
+// Get return limiter of the key, create one if not exist
+func (k *Keyed) Get(key string) Limiter {
+	k.mu.Lock()
+	now := k.clock.Now()
+	if k.idle > 0 && now.Sub(k.swept) >= k.idle {
+		k.evict(now)
+	}
+	e := k.entries[key]
+	if e == nil {
+		e = &keyedEntry{limiter: k.new()}
+		k.entries[key] = e
+	}
+	e.used = now
+	k.mu.Unlock()
+	return e.limiter
+}
+
+func (k *Keyed) Allow(key string) bool {
+	return k.Get(key).Allow()
+}
+
+func (k *Keyed) AllowN(key string, n int) bool {
+	return k.Get(key).AllowN(n)
+}
+
+// Len return count of keys
+func (k *Keyed) Len() int {
+	k.mu.Lock()
+	l := len(k.entries)
+	k.mu.Unlock()
+	return l
+}
+
+// Remove remove limiter of the key
+func (k *Keyed) Remove(key string) {
+	k.mu.Lock()
+	delete(k.entries, key)
+	k.mu.Unlock()
+}
+
+// Evict remove limiters idle longer than idle duration, return the count
+// of removed
+func (k *Keyed) Evict() int {
+	k.mu.Lock()
+	n := k.evict(k.clock.Now())
+	k.mu.Unlock()
+	return n
+}
+
+func (k *Keyed) evict(now time.Time) int {
+	var n int
+	for key, e := range k.entries {
+		if now.Sub(e.used) >= k.idle {
+			delete(k.entries, key)
+			n++
+		}
+	}
+	k.swept = now
+	return n
+}
+
+// RemoteIP return ip of request's remote address
+func RemoteIP(r *http.Request) string {
+	return http2.IpOfAddr(r.RemoteAddr)
+}
+
+// Handler limit requests by key, if key is nil, RemoteIP is used. Rejected
+// requests get status 429.
+func (k *Keyed) Handler(key func(*http.Request) string, next http.Handler) http.Handler {
+	if key == nil {
+		key = RemoteIP
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !k.Allow(key(r)) {
+			code := http.StatusTooManyRequests
+			http.Error(w, http.StatusText(code), code)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,147 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cosiner/gohper/testing2"
+	"github.com/cosiner/gohper/time2"
+)
+
+func TestTokenBucket(t *testing.T) {
+	tt := testing2.Wrap(t)
+	clock := time2.NewFakeClock(time.Unix(0, 0))
+	b := NewTokenBucketClock(clock, 2, 3)
+
+	tt.True(b.Allow())
+	tt.True(b.AllowN(2))
+	tt.False(b.Allow())
+	clock.Add(500 * time.Millisecond)
+	tt.True(b.Allow())
+	tt.False(b.Allow())
+	clock.Add(time.Hour)
+	tt.Eq(3.0, b.Tokens())
+	tt.False(b.AllowN(4))
+
+	r := b.ReserveN(4)
+	tt.False(r.OK())
+	r = b.ReserveN(3)
+	tt.True(r.OK())
+	tt.Eq(time.Duration(0), r.Delay())
+	r = b.ReserveN(2)
+	tt.Eq(time.Second, r.Delay())
+	r.Cancel()
+	tt.Eq(0.0, b.Tokens())
+	r.Cancel()
+	tt.Eq(0.0, b.Tokens())
+
+	// the later reservation keep it's delay, so only tokens not reserved
+	// by it are given back
+	r = b.ReserveN(2)
+	later := b.Reserve()
+	tt.Eq(1500*time.Millisecond, later.Delay())
+	r.Cancel()
+	tt.Eq(-2.0, b.Tokens())
+	tt.Eq(1500*time.Millisecond, later.Delay())
+	later.Cancel()
+	tt.Eq(-1.0, b.Tokens())
+	clock.Add(time.Hour)
+
+	inf := NewTokenBucketClock(clock, math.Inf(1), 0)
+	tt.True(inf.AllowN(100))
+	tt.Eq(2.0, Every(500*time.Millisecond))
+
+	zero := NewTokenBucketClock(clock, 0, 1)
+	tt.True(zero.Allow())
+	tt.False(zero.Reserve().OK())
+}
+
+func TestTokenBucketWait(t *testing.T) {
+	tt := testing2.Wrap(t)
+	clock := time2.NewFakeClock(time.Unix(0, 0))
+	b := NewTokenBucketClock(clock, 1, 1)
+	ctx := context.Background()
+
+	tt.Nil(b.Wait(ctx))
+	tt.Eq(ErrExceedsBurst, b.WaitN(ctx, 2))
+
+	done := make(chan error, 1)
+	go func() { done <- b.Wait(ctx) }()
+	clock.BlockUntil(1)
+	clock.Add(time.Second)
+	tt.Nil(<-done)
+
+	wall := time2.NewFakeClock(time.Now())
+	slow := NewTokenBucketClock(wall, Every(2*time.Hour), 1)
+	tt.True(slow.Allow())
+	deadline, cancel := context.WithDeadline(ctx, wall.Now().Add(time.Hour))
+	tt.Eq(ErrExceedsDeadline, slow.Wait(deadline))
+	cancel()
+	tt.Eq(0.0, slow.Tokens())
+
+	canceled, cancel := context.WithCancel(ctx)
+	go func() { done <- b.Wait(canceled) }()
+	clock.BlockUntil(1)
+	cancel()
+	tt.Eq(context.Canceled, <-done)
+}
+
+func TestSlidingWindow(t *testing.T) {
+	tt := testing2.Wrap(t)
+	clock := time2.NewFakeClock(time.Unix(0, 0))
+	w := NewSlidingWindowClock(clock, 4, time.Minute)
+
+	tt.True(w.AllowN(4))
+	tt.False(w.Allow())
+	clock.Add(time.Minute + 30*time.Second)
+	// half of previous window is counted
+	tt.Eq(2.0, w.Count())
+	tt.True(w.AllowN(2))
+	tt.False(w.Allow())
+	clock.Add(15 * time.Second)
+	tt.True(w.Allow())
+	clock.Add(3 * time.Minute)
+	tt.Eq(0.0, w.Count())
+	tt.True(w.AllowN(4))
+
+	defer tt.Recover()
+	NewSlidingWindow(1, 0)
+}
+
+func TestKeyed(t *testing.T) {
+	tt := testing2.Wrap(t)
+	clock := time2.NewFakeClock(time.Unix(0, 0))
+	k := NewKeyedClock(clock, time.Minute, func() Limiter {
+		return NewTokenBucketClock(clock, 0, 1)
+	})
+
+	tt.True(k.Allow("a"))
+	tt.False(k.Allow("a"))
+	tt.True(k.Allow("b"))
+	tt.Eq(2, k.Len())
+
+	clock.Add(30 * time.Second)
+	tt.False(k.Allow("a"))
+	clock.Add(40 * time.Second)
+	// b is idle and evicted
+	tt.True(k.Allow("c"))
+	tt.Eq(2, k.Len())
+	tt.False(k.Allow("a"))
+	clock.Add(time.Minute)
+	tt.Eq(2, k.Evict())
+	tt.True(k.Allow("a"))
+
+	h := k.Handler(nil, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, req)
+	tt.Eq(http.StatusOK, resp.Code)
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, req)
+	tt.Eq(http.StatusTooManyRequests, resp.Code)
+}
@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cosiner/gohper/time2"
+)
+
+// SlidingWindow allow at most limit events in any window, the count of
+// previous window is weighted by its overlap with the sliding window.
+type SlidingWindow struct {
+	mu     sync.Mutex
+	clock  time2.Clock
+	limit  int
+	window time.Duration
+	start  time.Time
+	prev   int
+	curr   int
+}
+
+// NewSlidingWindow create a limiter allow limit events per window, it panic if
+// window is not positive
+func NewSlidingWindow(limit int, window time.Duration) *SlidingWindow {
+	return NewSlidingWindowClock(time2.RealClock, limit, window)
+}
+
+func NewSlidingWindowClock(clock time2.Clock, limit int, window time.Duration) *SlidingWindow {
+	if window <= 0 {
+		panic("ratelimit: window must be positive")
+	}
+	return &SlidingWindow{
+		clock:  clock,
+		limit:  limit,
+		window: window,
+		start:  clock.Now(),
+	}
+}
+
+func (w *SlidingWindow) advance(now time.Time) {
+	elapsed := now.Sub(w.start)
+	if elapsed < w.window {
+		return
+	}
+	n := elapsed / w.window
+	if n == 1 {
+		w.prev = w.curr
+	} else {
+		w.prev = 0
+	}
+	w.curr = 0
+	w.start = w.start.Add(n * w.window)
+}
+
+func (w *SlidingWindow) count(now time.Time) float64 {
+	weight := 1 - float64(now.Sub(w.start))/float64(w.window)
+	return float64(w.prev)*weight + float64(w.curr)
+}
+
+// Count return estimated events in current sliding window
+func (w *SlidingWindow) Count() float64 {
+	w.mu.Lock()
+	now := w.clock.Now()
+	w.advance(now)
+	c := w.count(now)
+	w.mu.Unlock()
+	return c
+}
+
+func (w *SlidingWindow) Allow() bool {
+	return w.AllowN(1)
+}
+
+// AllowN report whether n events may happen now, they are counted only if
+// it's allowed
+func (w *SlidingWindow) AllowN(n int) bool {
+	w.mu.Lock()
+	now := w.clock.Now()
+	w.advance(now)
+	ok := w.count(now)+float64(n) <= float64(w.limit)
+	if ok {
+		w.curr += n
+	}
+	w.mu.Unlock()
+	return ok
+}
@@ -0,0 +1,87 @@
+package retry
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Backoff compute delay before a retry, attempt start from 1 for the first
+// retry, prev is the delay before previous retry, it's 0 for the first
+type Backoff interface {
+	Delay(attempt int, prev time.Duration) time.Duration
+}
+
+// Constant backoff always wait same duration
+type Constant time.Duration
+
+func (c Constant) Delay(int, time.Duration) time.Duration {
+	return time.Duration(c)
+}
+
+// Exponential backoff wait Initial*Multiplier^(attempt-1), randomized by
+// Jitter and capped by Max
+type Exponential struct {
+	// Initial default 100ms
+	Initial time.Duration
+	// Max is unlimited if 0
+	Max time.Duration
+	// Multiplier default 2
+	Multiplier float64
+	// Jitter randomize delay in [d*(1-Jitter), d*(1+Jitter)], in range [0, 1]
+	Jitter float64
+}
+
+func (e Exponential) Delay(attempt int, _ time.Duration) time.Duration {
+	initial, multiplier := e.Initial, e.Multiplier
+	if initial <= 0 {
+		initial = 100 * time.Millisecond
+	}
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+
+	// cap before jitter, otherwise overflowed delay become NaN
+	d := float64(capDelay(float64(initial)*math.Pow(multiplier, float64(attempt-1)), e.Max))
+	if e.Jitter > 0 {
+		d += d * e.Jitter * (2*rand.Float64() - 1)
+	}
+	return capDelay(d, e.Max)
+}
+
+// DecorrelatedJitter backoff wait random duration in [Base, prev*3], capped
+// by Max, it spreads retries of many clients better than Exponential
+type DecorrelatedJitter struct {
+	// Base default 100ms
+	Base time.Duration
+	// Max is unlimited if 0
+	Max time.Duration
+}
+
+func (j DecorrelatedJitter) Delay(_ int, prev time.Duration) time.Duration {
+	base := j.Base
+	if base <= 0 {
+		base = 100 * time.Millisecond
+	}
+	if prev < base {
+		prev = base
+	}
+
+	d := float64(base) + rand.Float64()*(3*float64(prev)-float64(base))
+	return capDelay(d, j.Max)
+}
+
+// capDelay convert d to duration not exceed max, NaN and overflowed values
+// are treated as max, if max is 0, math.MaxInt64 is used
+func capDelay(d float64, max time.Duration) time.Duration {
+	if max <= 0 {
+		max = math.MaxInt64
+	}
+	if math.IsNaN(d) || d >= float64(max) {
+		return max
+	}
+	if d < 0 {
+		return 0
+	}
+	return time.Duration(d)
+}
@@ -0,0 +1,44 @@
+package retry
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent mark a error as non-retryable
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+// IsPermanent check whether the error is marked by Permanent
+func IsPermanent(err error) bool {
+	_, is := err.(permanentError)
+	return is
+}
+
+// Unwrap return the original error if it's marked by Permanent
+func Unwrap(err error) error {
+	if e, is := err.(permanentError); is {
+		return e.err
+	}
+	return err
+}
+
+// Temporary is a classifier report whether a error has a Temporary method
+// return true, such as net.Error
+func Temporary(err error) bool {
+	e, is := err.(interface {
+		Temporary() bool
+	})
+	return is && e.Temporary()
+}
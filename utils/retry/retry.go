@@ -0,0 +1,155 @@
+// Package retry run operations repeatedly with backoff until they succeed,
+// fail permanently or exceed limits.
+package retry
+
+import (
+	"context"
+	"time"
+
+	"github.com/cosiner/gohper/time2"
+)
+
+// Attempt describe a failed attempt, it's passed to Policy.OnAttempt
+type Attempt struct {
+	// Num start from 1
+	Num     int
+	Err     error
+	Elapsed time.Duration
+	// Delay before next attempt, it's meaningless if Retry is false
+	Delay time.Duration
+	Retry bool
+}
+
+// Policy decide whether and when to retry
+type Policy struct {
+	// Backoff default Exponential{}
+	Backoff Backoff
+	// MaxAttempts is count of attempts include the first, unlimited if 0
+	MaxAttempts int
+	// MaxElapsed stop retry if next attempt would start after it since
+	// first attempt, unlimited if 0
+	MaxElapsed time.Duration
+	// Retryable classify errors, default all errors are retryable except
+	// errors marked by Permanent
+	Retryable func(error) bool
+	// OnAttempt is called after each failed attempt
+	OnAttempt func(Attempt)
+	// Clock default time2.RealClock
+	Clock time2.Clock
+}
+
+// Default policy try at most 5 times with exponential backoff
+var Default = &Policy{
+	Backoff:     Exponential{Initial: 100 * time.Millisecond, Max: 10 * time.Second, Jitter: 0.2},
+	MaxAttempts: 5,
+}
+
+// Do run fn with Default policy
+func Do(ctx context.Context, fn func(context.Context) error) error {
+	return Default.Do(ctx, fn)
+}
+
+func (p *Policy) clock() time2.Clock {
+	if p.Clock == nil {
+		return time2.RealClock
+	}
+	return p.Clock
+}
+
+// Do run fn until it success, return a non-retryable error or limits exceed,
+// the last error is returned. If context is done during waiting, context's
+// error is returned.
+func (p *Policy) Do(ctx context.Context, fn func(context.Context) error) error {
+	s := p.Start()
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		delay, ok := s.Next(err)
+		if !ok {
+			return Unwrap(err)
+		}
+		if err := s.sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// State track attempts of a policy, it's useful for retry loops managed by
+// callers, State is not safe for concurrent use
+type State struct {
+	policy  *Policy
+	attempt int
+	prev    time.Duration
+	start   time.Time
+}
+
+// Start create a State, the clock for MaxElapsed starts now
+func (p *Policy) Start() *State {
+	return &State{
+		policy: p,
+		start:  p.clock().Now(),
+	}
+}
+
+// Reset reset attempts after a success
+func (s *State) Reset() {
+	s.attempt = 0
+	s.prev = 0
+	s.start = s.policy.clock().Now()
+}
+
+// Next record a failed attempt, return delay before next attempt and
+// whether to retry
+func (s *State) Next(err error) (time.Duration, bool) {
+	p := s.policy
+	s.attempt++
+	a := Attempt{
+		Num:     s.attempt,
+		Err:     err,
+		Elapsed: p.clock().Since(s.start),
+	}
+
+	a.Retry = !IsPermanent(err) && (p.Retryable == nil || p.Retryable(err)) &&
+		(p.MaxAttempts <= 0 || s.attempt < p.MaxAttempts)
+	if a.Retry {
+		backoff := p.Backoff
+		if backoff == nil {
+			backoff = Exponential{}
+		}
+		a.Delay = backoff.Delay(s.attempt, s.prev)
+		if p.MaxElapsed > 0 && a.Elapsed+a.Delay > p.MaxElapsed {
+			a.Retry = false
+		}
+		s.prev = a.Delay
+	}
+	if p.OnAttempt != nil {
+		p.OnAttempt(a)
+	}
+	return a.Delay, a.Retry
+}
+
+// Sleep wait the delay returned by Next
+func (s *State) Sleep(delay time.Duration) {
+	if delay > 0 {
+		s.policy.clock().Sleep(delay)
+	}
+}
+
+func (s *State) sleep(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return nil
+	}
+	timer := s.policy.clock().NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,155 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/cosiner/gohper/testing2"
+	"github.com/cosiner/gohper/time2"
+)
+
+func TestBackoff(t *testing.T) {
+	tt := testing2.Wrap(t)
+	tt.Eq(time.Second, Constant(time.Second).Delay(3, 0))
+
+	e := Exponential{Initial: time.Second, Max: 5 * time.Second}
+	tt.Eq(time.Second, e.Delay(1, 0))
+	tt.Eq(2*time.Second, e.Delay(2, 0))
+	tt.Eq(4*time.Second, e.Delay(3, 0))
+	tt.Eq(5*time.Second, e.Delay(4, 0))
+	tt.Eq(5*time.Second, e.Delay(1000, 0))
+	tt.Eq(100*time.Millisecond, Exponential{}.Delay(1, 0))
+
+	e.Jitter = 0.5
+	d := e.Delay(2000, 0)
+	tt.True(d >= 2500*time.Millisecond && d <= 5*time.Second)
+	tt.True(Exponential{Jitter: 0.5}.Delay(2000, 0) >= math.MaxInt64/2)
+	tt.Eq(time.Duration(math.MaxInt64), Exponential{}.Delay(2000, 0))
+	for i := 0; i < 100; i++ {
+		d := e.Delay(2, 0)
+		tt.True(d >= time.Second && d <= 3*time.Second)
+	}
+
+	j := DecorrelatedJitter{Base: time.Second, Max: 10 * time.Second}
+	prev := time.Duration(0)
+	for i := 0; i < 100; i++ {
+		d := j.Delay(i+1, prev)
+		max := 3 * prev
+		if max < 3*time.Second {
+			max = 3 * time.Second
+		}
+		if max > 10*time.Second {
+			max = 10 * time.Second
+		}
+		tt.True(d >= time.Second && d <= max)
+		prev = d
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	tt := testing2.Wrap(t)
+	errFail := errors.New("fail")
+	ctx := context.Background()
+
+	var attempts []Attempt
+	p := &Policy{
+		Backoff:     Constant(0),
+		MaxAttempts: 3,
+		OnAttempt:   func(a Attempt) { attempts = append(attempts, a) },
+	}
+	var calls int
+	tt.Eq(errFail, p.Do(ctx, func(context.Context) error {
+		calls++
+		return errFail
+	}))
+	tt.Eq(3, calls)
+	tt.Eq(3, len(attempts))
+	tt.True(attempts[1].Retry)
+	tt.Eq(3, attempts[2].Num)
+	tt.False(attempts[2].Retry)
+
+	calls = 0
+	tt.Nil(p.Do(ctx, func(context.Context) error {
+		if calls++; calls < 3 {
+			return errFail
+		}
+		return nil
+	}))
+
+	calls = 0
+	tt.Eq(errFail, p.Do(ctx, func(context.Context) error {
+		calls++
+		return Permanent(errFail)
+	}))
+	tt.Eq(1, calls)
+
+	calls = 0
+	p.Retryable = func(err error) bool { return err != errFail }
+	tt.Eq(errFail, p.Do(ctx, func(context.Context) error {
+		calls++
+		return errFail
+	}))
+	tt.Eq(1, calls)
+}
+
+func TestPolicyWait(t *testing.T) {
+	tt := testing2.Wrap(t)
+	clock := time2.NewFakeClock(time.Unix(0, 0))
+	errFail := errors.New("fail")
+	p := &Policy{
+		Backoff:    Constant(time.Minute),
+		MaxElapsed: 150 * time.Second,
+		Clock:      clock,
+	}
+
+	var calls int
+	done := make(chan error, 1)
+	go func() {
+		done <- p.Do(context.Background(), func(context.Context) error {
+			calls++
+			return errFail
+		})
+	}()
+	clock.BlockUntil(1)
+	clock.Add(time.Minute)
+	clock.BlockUntil(1)
+	clock.Add(time.Minute)
+	// third delay would exceed MaxElapsed
+	tt.Eq(errFail, <-done)
+	tt.Eq(3, calls)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		done <- p.Do(ctx, func(context.Context) error { return errFail })
+	}()
+	clock.BlockUntil(1)
+	cancel()
+	tt.Eq(context.Canceled, <-done)
+
+	s := p.Start()
+	_, ok := s.Next(errFail)
+	tt.True(ok)
+	clock.Add(time.Hour)
+	_, ok = s.Next(errFail)
+	tt.False(ok)
+	s.Reset()
+	_, ok = s.Next(errFail)
+	tt.True(ok)
+}
+
+type tempError bool
+
+func (e tempError) Error() string   { return "temp" }
+func (e tempError) Temporary() bool { return bool(e) }
+
+func TestTemporary(t *testing.T) {
+	tt := testing2.Wrap(t)
+	tt.True(Temporary(tempError(true)))
+	tt.False(Temporary(tempError(false)))
+	tt.False(Temporary(errors.New("x")))
+	tt.Nil(Permanent(nil))
+	tt.True(IsPermanent(Permanent(tempError(true))))
+}
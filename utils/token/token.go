@@ -19,6 +19,7 @@ const (
 )
 
 type Cipher struct {
+	clock   time2.Clock
 	signKey []byte
 	ttl     time.Duration
 	hash    func() hash.Hash
@@ -26,9 +27,10 @@ type Cipher struct {
 	hdrLen  int
 }
 
-func newCipher(signKey []byte, ttl time.Duration, hash func() hash.Hash) *Cipher {
+func newCipher(clock time2.Clock, signKey []byte, ttl time.Duration, hash func() hash.Hash) *Cipher {
 	sigLen := hash().Size()
 	return &Cipher{
+		clock:   clock,
 		signKey: signKey,
 		ttl:     ttl,
 		hash:    hash,
@@ -38,7 +40,12 @@ func newCipher(signKey []byte, ttl time.Duration, hash func() hash.Hash) *Cipher
 }
 
 func NewCipher(signKey []byte, ttl time.Duration, hash func() hash.Hash, encs ...encoding.Encoding) encoding.Encoding {
-	return encoding.Pipe(encs).Prepend(newCipher(signKey, ttl, hash))
+	return NewCipherClock(time2.RealClock, signKey, ttl, hash, encs...)
+}
+
+// NewCipherClock is same as NewCipher, but use the clock to check expiration
+func NewCipherClock(clock time2.Clock, signKey []byte, ttl time.Duration, hash func() hash.Hash, encs ...encoding.Encoding) encoding.Encoding {
+	return encoding.Pipe(encs).Prepend(newCipher(clock, signKey, ttl, hash))
 }
 
 // | signature | deadline | str
@@ -56,7 +63,7 @@ func (c *Cipher) encrypt(deadline uint64, b []byte) []byte {
 }
 
 func (c *Cipher) Encode(b []byte) []byte {
-	deadline := uint64(time2.Now().Add(c.ttl).Unix())
+	deadline := uint64(c.clock.Now().Add(c.ttl).Unix())
 	return c.encrypt(deadline, b)
 }
 
@@ -66,7 +73,7 @@ func (c *Cipher) Decode(b []byte) ([]byte, error) {
 	}
 
 	deadline := binary.BigEndian.Uint64(b[c.sigLen:c.hdrLen])
-	if c.ttl != 0 && uint64(time2.Now().Unix()) > deadline {
+	if c.ttl != 0 && uint64(c.clock.Now().Unix()) > deadline {
 		return nil, ErrExpiredKey
 	}
 
@@ -7,6 +7,7 @@ import (
 
 	"github.com/cosiner/gohper/encoding"
 	"github.com/cosiner/gohper/testing2"
+	"github.com/cosiner/gohper/time2"
 	"github.com/cosiner/gohper/unsafe2"
 	"crypto/hmac"
 )
@@ -30,6 +31,21 @@ func TestCipher(t *testing.T) {
 	}
 }
 
+func TestCipherExpire(t *testing.T) {
+	tt := testing2.Wrap(t)
+	clock := time2.NewFakeClock(time.Unix(1000, 0))
+	c := NewCipherClock(clock, []byte("12345"), time.Minute, md5.New)
+
+	tok := c.Encode([]byte("a"))
+	clock.Add(time.Minute)
+	ds, err := c.Decode(tok)
+	tt.Nil(err).Eq("a", string(ds))
+
+	clock.Add(time.Second)
+	_, err = c.Decode(tok)
+	tt.Eq(ErrExpiredKey, err)
+}
+
 var cipher = NewCipher([]byte("12345"), time.Second*100, md5.New)
 var data = []byte("abcdefghijklmn")
 var encData = cipher.Encode(data)
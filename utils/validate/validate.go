@@ -1,6 +1,8 @@
 package validate
 
 import (
+	"strconv"
+
 	"github.com/cosiner/gohper/errors"
 )
 
@@ -70,28 +72,73 @@ func (vc ValidChain) StrictValidateM(s ...string) error {
 }
 
 func (vc ValidChain) ValidateM(s ...string) error {
+	var err error
+	vc.each(s, func(_ int, v Validator, s string) bool {
+		err = v(s)
+
+		return err == nil
+	})
+
+	return err
+}
+
+// ValidateAll validate string with all validators, return a
+// errors.MultiError contains all failures or nil
+func (vc ValidChain) ValidateAll(s string) error {
+	var errs errors.MultiError
+	for _, v := range vc {
+		errs.Append(v(s))
+	}
+
+	return errs.ErrorOrNil()
+}
+
+// ValidateAllM is same as ValidateM, but don't stop at the first failure,
+// return a errors.MultiError keyed by string index or nil
+func (vc ValidChain) ValidateAllM(s ...string) error {
+	var errs errors.MultiError
+	vc.each(s, func(i int, v Validator, s string) bool {
+		errs.AppendKey(strconv.Itoa(i), v(s))
+
+		return true
+	})
+
+	return errs.ErrorOrNil()
+}
+
+// each pair validators with strings, if there are more strings, last validator
+// process all remains strings, otherwise remains validators process last string.
+// fn accept index of string, return false to stop
+func (vc ValidChain) each(s []string, fn func(int, Validator, string) bool) {
 	l1, l2 := len(vc)-1, len(s)-1
-	var (
-		i   int
-		err error
-	)
 	if l1 < 0 || l2 < 0 {
-	} else if l1 <= l2 {
-		for i = 0; err == nil && i <= l1; i++ {
-			err = vc[i](s[i])
+		return
+	}
+
+	var i int
+	if l1 <= l2 {
+		for i = 0; i <= l1; i++ {
+			if !fn(i, vc[i], s[i]) {
+				return
+			}
 		}
 
-		for i = l1 + 1; err == nil && i <= l2; i++ {
-			err = vc[l1](s[i])
-		} // last validator process all remains string
+		for i = l1 + 1; i <= l2; i++ {
+			if !fn(i, vc[l1], s[i]) {
+				return
+			}
+		}
 	} else {
-		for i = 0; err == nil && i <= l2; i++ {
-			err = vc[i](s[i])
+		for i = 0; i <= l2; i++ {
+			if !fn(i, vc[i], s[i]) {
+				return
+			}
 		}
 
-		for i = l2 + 1; err == nil && i <= l1; i++ {
-			err = vc[i](s[l2])
-		} // remains validators process last string
+		for i = l2 + 1; i <= l1; i++ {
+			if !fn(l2, vc[i], s[l2]) {
+				return
+			}
+		}
 	}
-	return err
 }
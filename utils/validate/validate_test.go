@@ -85,3 +85,20 @@ func TestValidateM(t *testing.T) {
 	tt.Eq(err, v("a23", "a"))
 	tt.Nil(v("a23", "1234"))
 }
+
+func TestValidateAll(t *testing.T) {
+	tt := testing2.Wrap(t)
+
+	errLength := errors.Err("incorrect length")
+	errChars := errors.Err("incorrect chars")
+	vc := New(ValidLength(3, 10, errLength), ValidChars("0123456789", errChars))
+
+	tt.Nil(vc.ValidateAll("123"))
+	err := vc.ValidateAll("a")
+	tt.Eq("2 errors: incorrect length; incorrect chars", err.Error())
+
+	tt.Nil(vc.ValidateAllM("abc", "123"))
+	err = vc.ValidateAllM("a", "b", "123", "c")
+	tt.Eq("3 errors: 0: incorrect length; 1: incorrect chars; 3: incorrect chars", err.Error())
+	tt.True(errors.Is(err, errChars))
+}